@@ -0,0 +1,499 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rafaelmartins/b8r/internal/androidtv"
+	"github.com/rafaelmartins/b8r/internal/cleanup"
+	"github.com/rafaelmartins/b8r/internal/cli"
+	"github.com/rafaelmartins/b8r/internal/config"
+)
+
+var (
+	atvKeyDirections = map[string]androidtv.KeyDirection{
+		"press":   androidtv.KeyPress,
+		"hold":    androidtv.KeyHold,
+		"release": androidtv.KeyRelease,
+	}
+
+	oAndroidTv = &cli.StringOption{
+		Name:    'T',
+		Default: "",
+		Help:    "comma separated list of android-tv devices to control (default: all devices)",
+		Metavar: "NAMES",
+		CompletionHandler: func(cur string) []string {
+			prefix := ""
+			if i := strings.LastIndex(cur, ","); i >= 0 {
+				prefix, cur = cur[:i+1], cur[i+1:]
+			}
+			rv := []string{}
+			for _, d := range completeAndroidTvDevices(cur) {
+				rv = append(rv, prefix+d)
+			}
+			return rv
+		},
+	}
+	oAtvKeyDirection = &cli.StringOption{
+		Name:    'd',
+		Default: "press",
+		Help:    "key direction (press, hold, release or long)",
+		Metavar: "DIRECTION",
+		CompletionHandler: func(cur string) []string {
+			rv := []string{}
+			for _, k := range []string{"press", "hold", "release", "long"} {
+				if strings.HasPrefix(k, cur) {
+					rv = append(rv, k)
+				}
+			}
+			return rv
+		},
+	}
+	aAtvKeyCodes = &cli.Argument{
+		Name:      "keycode",
+		Required:  true,
+		Remaining: true,
+		Help:      "one or more key codes to send (e.g. KEYCODE_HOME)",
+		CompletionHandler: func(prev string, cur string) []string {
+			rv := []string{}
+			for _, k := range androidtv.KeyCodes() {
+				if strings.HasPrefix(k, strings.ToUpper(cur)) {
+					rv = append(rv, k)
+				}
+			}
+			return rv
+		},
+	}
+	aAtvVolume = &cli.Argument{
+		Name:     "level",
+		Required: true,
+		Help:     "`up', `down' or the volume level to set",
+		CompletionHandler: func(prev string, cur string) []string {
+			rv := []string{}
+			for _, k := range []string{"up", "down"} {
+				if strings.HasPrefix(k, cur) {
+					rv = append(rv, k)
+				}
+			}
+			return rv
+		},
+	}
+	aAtvLink = &cli.Argument{
+		Name:     "link",
+		Required: true,
+		Help:     "app deep link to launch (e.g. https://www.youtube.com)",
+	}
+
+	cAtvKey = &cli.Cli{
+		Name: "key",
+		Help: "send key codes to android-tv device",
+		Options: []cli.Option{
+			oEvents,
+			oAndroidTv,
+			oAtvKeyDirection,
+		},
+		Arguments: []*cli.Argument{
+			aAtvKeyCodes,
+		},
+	}
+	cAtvVolume = &cli.Cli{
+		Name: "volume",
+		Help: "change android-tv device volume",
+		Options: []cli.Option{
+			oEvents,
+			oAndroidTv,
+		},
+		Arguments: []*cli.Argument{
+			aAtvVolume,
+		},
+	}
+	cAtvLaunch = &cli.Cli{
+		Name: "launch",
+		Help: "launch app on android-tv device",
+		Options: []cli.Option{
+			oEvents,
+			oAndroidTv,
+		},
+		Arguments: []*cli.Argument{
+			aAtvLink,
+		},
+	}
+	cAtvStatus = &cli.Cli{
+		Name: "status",
+		Help: "show android-tv device status",
+		Options: []cli.Option{
+			oEvents,
+			oAndroidTv,
+		},
+	}
+	cAtvDiscover = &cli.Cli{
+		Name: "discover",
+		Help: "discover android-tv devices in the local network",
+	}
+	cAtvCertShow = &cli.Cli{
+		Name: "show",
+		Help: "show android-tv certificate details",
+		Options: []cli.Option{
+			oAndroidTv,
+		},
+	}
+	cAtvCertRotate = &cli.Cli{
+		Name: "rotate",
+		Help: "create a new android-tv certificate and pair again, keeping a backup of the old one",
+		Options: []cli.Option{
+			oEvents,
+			oAndroidTv,
+			oPairingCode,
+		},
+	}
+	cAtvCert = &cli.Cli{
+		Name: "cert",
+		Help: "manage android-tv certificates",
+		Commands: []*cli.Cli{
+			cAtvCertShow,
+			cAtvCertRotate,
+		},
+	}
+	cAtv = &cli.Cli{
+		Name: "atv",
+		Help: "control android-tv device",
+		Commands: []*cli.Cli{
+			cAtvKey,
+			cAtvVolume,
+			cAtvLaunch,
+			cAtvStatus,
+			cAtvDiscover,
+			cAtvCert,
+		},
+	}
+)
+
+const atvCertExpiryWarning = 30 * 24 * time.Hour
+
+func isAtvCommand(cmd *cli.Cli) bool {
+	return cmd == cAtv || slices.Contains(cAtv.Commands, cmd) || slices.Contains(cAtvCert.Commands, cmd)
+}
+
+func completeAndroidTvDevices(cur string) []string {
+	c, err := config.New()
+	if err != nil {
+		return nil
+	}
+
+	rv := []string{}
+	for _, d := range c.ListAndroidTvDevices() {
+		if strings.HasPrefix(d, cur) {
+			rv = append(rv, d)
+		}
+	}
+	return rv
+}
+
+func selectAndroidTvDevices(conf *config.Config, names []string) ([]*config.AndroidTv, error) {
+	if names == nil {
+		return conf.GetAndroidTvDevices(), nil
+	}
+
+	rv := []*config.AndroidTv{}
+	for _, name := range names {
+		d := conf.GetAndroidTvDevice(strings.TrimSpace(name))
+		if d == nil {
+			return nil, fmt.Errorf("android-tv device not found: %s", name)
+		}
+		rv = append(rv, d)
+	}
+	return rv, nil
+}
+
+func discoverAndroidTv() ([]*androidtv.DiscoveredDevice, error) {
+	fmt.Fprintln(os.Stderr, "Discovering android-tv devices ...")
+	devices, err := androidtv.Discover(3 * time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if len(devices) == 0 {
+		return nil, fmt.Errorf("no android-tv devices found")
+	}
+	return devices, nil
+}
+
+func pickAndroidTvHost(conf *config.Config, name string) (*config.AndroidTv, error) {
+	devices, err := discoverAndroidTv()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, dev := range devices {
+		fmt.Printf("%d) %s (%s", i+1, dev.Name, dev.Host)
+		if dev.Model != "" {
+			fmt.Printf(", %s", dev.Model)
+		}
+		fmt.Println(")")
+	}
+
+	fmt.Printf("Please choose the device to pair as `%s' [1-%d]: ", name, len(devices))
+	rv, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	idx, err := strconv.Atoi(strings.TrimSpace(rv))
+	if err != nil || idx < 1 || idx > len(devices) {
+		return nil, fmt.Errorf("invalid choice: %s", strings.TrimSpace(rv))
+	}
+
+	if err := conf.SetAndroidTvHost(name, devices[idx-1].Host); err != nil {
+		return nil, err
+	}
+	return conf.GetAndroidTvDevice(name), nil
+}
+
+func openAndroidTv(conf *config.Config, d *config.AndroidTv, dumpEvents bool) (*androidtv.Remote, error) {
+	if d.Host == "" {
+		return nil, fmt.Errorf("android-tv host not configured: %s", d.Name)
+	}
+
+	certFile, exists := conf.GetAndroidTvCertificate(d.Name)
+	if !exists {
+		return nil, fmt.Errorf("android-tv certificate not found, please pair by calling this binary with `-p %s`", d.Name)
+	}
+
+	cert, err := androidtv.OpenCertificate(certFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if info, err := androidtv.GetCertificateInfo(cert); err == nil {
+		if status := info.Status(time.Now(), atvCertExpiryWarning); status != "valid" {
+			log.Printf("warning: android-tv %s: certificate %s, please run `b8r atv cert rotate -T %s`", d.Name, status, d.Name)
+		}
+	}
+
+	atv, err := androidtv.NewRemote(d.Host, cert, dumpEvents)
+	if err != nil {
+		return nil, err
+	}
+	cleanup.Register(atv)
+
+	go func() {
+		cleanup.Check(atv.Listen())
+	}()
+
+	return atv, nil
+}
+
+func atvCertShow(conf *config.Config, d *config.AndroidTv) error {
+	certFile, exists := conf.GetAndroidTvCertificate(d.Name)
+	fmt.Printf("File:        %s\n", certFile)
+	if !exists {
+		fmt.Println("Status:      not paired")
+		return nil
+	}
+
+	cert, err := androidtv.OpenCertificate(certFile)
+	if err != nil {
+		return err
+	}
+
+	info, err := androidtv.GetCertificateInfo(cert)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Subject:     %s\n", info.Subject)
+	fmt.Printf("Fingerprint: %s\n", info.Fingerprint)
+	fmt.Printf("Not before:  %s\n", info.NotBefore.Local().Format(time.RFC3339))
+	fmt.Printf("Not after:   %s\n", info.NotAfter.Local().Format(time.RFC3339))
+	fmt.Printf("Status:      %s\n", info.Status(time.Now(), atvCertExpiryWarning))
+	return nil
+}
+
+func atvCertRotate(conf *config.Config, d *config.AndroidTv) error {
+	if d.Host == "" {
+		return fmt.Errorf("android-tv host not configured: %s", d.Name)
+	}
+
+	certFile, exists := conf.GetAndroidTvCertificate(d.Name)
+	backup := ""
+	if exists {
+		backup = fmt.Sprintf("%s.%s.bak", certFile, time.Now().Format("20060102150405"))
+		if err := os.Rename(certFile, backup); err != nil {
+			return err
+		}
+		fmt.Printf("Backup:      %s\n", backup)
+	}
+
+	if err := pairAndroidTv(conf, d, nil, true, 0); err != nil {
+		if backup != "" {
+			if err := os.Rename(backup, certFile); err != nil {
+				log.Printf("error: android-tv %s: failed to restore certificate backup: %s", d.Name, err)
+			}
+		}
+		return err
+	}
+	fmt.Println("Paired successfully")
+	return nil
+}
+
+func atvCommand(cmd *cli.Cli) {
+	if cmd == cAtv || cmd == cAtvCert {
+		cmd.Usage(false, "command required")
+		cleanup.Exit(1)
+	}
+
+	if cmd == cAtvDiscover {
+		devices, err := discoverAndroidTv()
+		cleanup.Check(err)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tADDRESS\tMODEL")
+		for _, dev := range devices {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", dev.Name, dev.Host, dev.Model)
+		}
+		cleanup.Check(w.Flush())
+		return
+	}
+
+	conf, err := config.New()
+	cleanup.Check(err)
+
+	var names []string
+	if oAndroidTv.IsSet() {
+		names = strings.Split(oAndroidTv.GetValue(), ",")
+	}
+
+	devices, err := selectAndroidTvDevices(conf, names)
+	cleanup.Check(err)
+
+	if len(devices) == 0 {
+		cleanup.Check("android-tv device not configured")
+	}
+
+	switch cmd {
+	case cAtvCertShow:
+		for i, d := range devices {
+			if len(devices) > 1 {
+				if i > 0 {
+					fmt.Println()
+				}
+				fmt.Printf("%s:\n", d.Name)
+			}
+			cleanup.Check(atvCertShow(conf, d))
+		}
+		return
+
+	case cAtvCertRotate:
+		for i, d := range devices {
+			if len(devices) > 1 {
+				if i > 0 {
+					fmt.Println()
+				}
+				fmt.Printf("%s:\n", d.Name)
+			}
+			cleanup.Check(atvCertRotate(conf, d))
+		}
+		return
+	}
+
+	connect := func(d *config.AndroidTv) *androidtv.Remote {
+		atv, err := openAndroidTv(conf, d, oEvents.GetValue())
+		cleanup.Check(err)
+		cleanup.Check(atv.WaitStarted(5 * time.Second))
+		return atv
+	}
+
+	switch cmd {
+	case cAtvKey:
+		long := oAtvKeyDirection.GetValue() == "long"
+		dir, found := atvKeyDirections[oAtvKeyDirection.GetValue()]
+		if !found && !long {
+			cmd.Usage(false, fmt.Sprintf("invalid key direction: %s", oAtvKeyDirection.GetValue()))
+			cleanup.Exit(1)
+		}
+
+		codes := []string{}
+		for _, code := range aAtvKeyCodes.GetValues() {
+			code = strings.ToUpper(code)
+			if !strings.HasPrefix(code, "KEYCODE_") {
+				code = "KEYCODE_" + code
+			}
+			codes = append(codes, code)
+		}
+
+		for _, d := range devices {
+			atv := connect(d)
+
+			for _, code := range codes {
+				if !long {
+					cleanup.Check(atv.SendKey(code, dir))
+					continue
+				}
+
+				cleanup.Check(atv.SendKey(code, androidtv.KeyHold))
+				time.Sleep(time.Second)
+				cleanup.Check(atv.SendKey(code, androidtv.KeyRelease))
+			}
+		}
+
+	case cAtvVolume:
+		v := aAtvVolume.GetValue()
+		level := uint64(0)
+		if v != "up" && v != "down" {
+			level, err = strconv.ParseUint(v, 10, 32)
+			if err != nil {
+				cmd.Usage(false, fmt.Sprintf("invalid volume level: %s", v))
+				cleanup.Exit(1)
+			}
+		}
+
+		for _, d := range devices {
+			atv := connect(d)
+
+			switch v {
+			case "up":
+				cleanup.Check(atv.VolumeUp())
+			case "down":
+				cleanup.Check(atv.VolumeDown())
+			default:
+				cleanup.Check(atv.SetVolume(uint32(level)))
+			}
+		}
+
+	case cAtvLaunch:
+		for _, d := range devices {
+			cleanup.Check(connect(d).LaunchApp(aAtvLink.GetValue()))
+		}
+
+	case cAtvStatus:
+		for i, d := range devices {
+			state, err := connect(d).WaitState(5 * time.Second)
+			cleanup.Check(err)
+
+			if len(devices) > 1 {
+				if i > 0 {
+					fmt.Println()
+				}
+				fmt.Printf("%s:\n", d.Name)
+			}
+
+			app := state.AppPackage
+			if app == "" {
+				app = "unknown"
+			}
+			power := "standby"
+			if state.PowerOn {
+				power = "on"
+			}
+			fmt.Printf("Power:  %s\n", power)
+			fmt.Printf("App:    %s\n", app)
+			fmt.Printf("Volume: %d / %d\n", state.VolumeLevel, state.VolumeMax)
+			fmt.Printf("Muted:  %t\n", state.VolumeMuted)
+		}
+	}
+}
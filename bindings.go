@@ -0,0 +1,17 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/rafaelmartins/b8r/internal/config"
+	"github.com/rafaelmartins/b8r/internal/handlers"
+)
+
+func bindActions(conf *config.Config) error {
+	for _, b := range conf.Bindings {
+		if err := handlers.Bind(b.Button, b.Press, b.Action); err != nil {
+			return fmt.Errorf("binding %d/%s: %w", b.Button, b.Press, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rafaelmartins/b8r/internal/cleanup"
+	"github.com/rafaelmartins/b8r/internal/cli"
+	"github.com/rafaelmartins/b8r/internal/control"
+	"github.com/rafaelmartins/b8r/internal/registry"
+)
+
+var (
+	oCtlSession = &cli.StringOption{
+		Name:    's',
+		Default: "",
+		Help:    "id of the session to control (default: most recent session)",
+		Metavar: "ID",
+		CompletionHandler: func(cur string) []string {
+			sessions, err := registry.List()
+			if err != nil {
+				return nil
+			}
+			rv := []string{}
+			for _, s := range sessions {
+				if _, found := s.Sockets["control"]; found && strings.HasPrefix(s.ID, cur) {
+					rv = append(rv, s.ID)
+				}
+			}
+			return rv
+		},
+	}
+	aCtlCommand = &cli.Argument{
+		Name:      "command",
+		Required:  true,
+		Remaining: true,
+		Help:      "command and arguments to send to the session (e.g. pair 1A2B3C)",
+	}
+
+	cCtl = &cli.Cli{
+		Name: "ctl",
+		Help: "send command to a running session",
+		Options: []cli.Option{
+			oCtlSession,
+		},
+		Arguments: []*cli.Argument{
+			aCtlCommand,
+		},
+	}
+)
+
+func ctlCommand() {
+	sessions, err := registry.List()
+	cleanup.Check(err)
+
+	socket := ""
+	for _, s := range sessions {
+		sock, found := s.Sockets["control"]
+		if !found {
+			continue
+		}
+		if id := oCtlSession.GetValue(); id != "" && id != s.ID {
+			continue
+		}
+		socket = sock
+	}
+	if socket == "" {
+		cleanup.Check("no running session found")
+	}
+
+	rv, err := control.Call(socket, aCtlCommand.GetValues()...)
+	cleanup.Check(err)
+
+	if rv != "" {
+		fmt.Println(rv)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/rafaelmartins/b8r/internal/cleanup"
+	"github.com/rafaelmartins/b8r/internal/cli"
+	"github.com/rafaelmartins/b8r/internal/config"
+	"github.com/rafaelmartins/b8r/internal/display"
+	"github.com/rafaelmartins/b8r/internal/handlers"
+)
+
+var (
+	oLayoutWidth = &cli.StringOption{
+		Name:    'w',
+		Default: "21",
+		Help:    "display width, in characters",
+		Metavar: "WIDTH",
+	}
+
+	cLayout = &cli.Cli{
+		Name: "layout",
+		Help: "preview the configured display pages",
+		Options: []cli.Option{
+			oLayoutWidth,
+		},
+	}
+
+	layoutSample = map[string]string{
+		"title":      "b8r",
+		"guards":     "TV:connected MP",
+		"source":     "local",
+		"table":      "movies",
+		"index":      "12 / 345",
+		"atv-app":    "google.android.youtube.tv",
+		"atv-volume": "V:12",
+		"atv-1":      "living-room: google.android.youtube.tv V:12",
+		"atv-2":      "bedroom: - ",
+		"current":    "holidays/2024/a-rather-long-file-name.mkv",
+		"next":       "holidays/2024/another-file.mkv",
+		"status":     "A-B 12.0-30.5 Ch 2/5",
+	}
+)
+
+func displayPages(conf *config.Config) ([]*display.Page, error) {
+	rv := []*display.Page{}
+	for i, p := range conf.Display.Pages {
+		name := p.Name
+		if name == "" {
+			name = strconv.Itoa(i + 1)
+		}
+
+		page := &display.Page{
+			Name: name,
+		}
+		for _, l := range p.Lines {
+			if l == nil {
+				page.Lines = append(page.Lines, nil)
+				continue
+			}
+
+			align, err := display.ParseAlign(l.Align)
+			if err != nil {
+				return nil, fmt.Errorf("display page %s: %w", name, err)
+			}
+			page.Lines = append(page.Lines, &display.Line{
+				Text:    l.Text,
+				Right:   l.Right,
+				Align:   align,
+				Marquee: l.Marquee,
+			})
+		}
+		rv = append(rv, page)
+	}
+	return rv, nil
+}
+
+func openDisplay(conf *config.Config, out display.Output, title string) (*display.Layout, error) {
+	pages, err := displayPages(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	rv, err := display.New(out, pages)
+	if err != nil {
+		return nil, err
+	}
+	cleanup.Register(rv)
+
+	if err := rv.Set("title", title); err != nil {
+		return nil, err
+	}
+	handlers.SetDisplay(rv)
+	return rv, nil
+}
+
+func layoutCommand() {
+	width, err := strconv.Atoi(oLayoutWidth.GetValue())
+	if err != nil || width <= 0 || width > 255 {
+		cleanup.Check(fmt.Errorf("invalid display width: %s", oLayoutWidth.GetValue()))
+	}
+
+	conf, err := config.New()
+	cleanup.Check(err)
+
+	pages, err := displayPages(conf)
+	cleanup.Check(err)
+
+	v := display.NewVirtual(width)
+	l, err := display.New(v, pages)
+	cleanup.Check(err)
+	defer l.Close()
+
+	for k, val := range layoutSample {
+		cleanup.Check(l.Set(k, val))
+	}
+
+	for i := range l.PageCount() {
+		if i > 0 {
+			cleanup.Check(l.NextPage(1))
+			fmt.Println()
+		}
+		fmt.Printf("%s\n%s", l.PageName(), v)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rafaelmartins/b8r/internal/cleanup"
+	"github.com/rafaelmartins/b8r/internal/cli"
+	"github.com/rafaelmartins/b8r/internal/config"
+	"github.com/rafaelmartins/b8r/internal/favorites"
+)
+
+var (
+	oFavFormat = &cli.StringOption{
+		Name:    'f',
+		Default: "json",
+		Help:    "export format (json or m3u)",
+		Metavar: "FORMAT",
+		CompletionHandler: func(cur string) []string {
+			rv := []string{}
+			for _, f := range []string{"json", "m3u"} {
+				if strings.HasPrefix(f, cur) {
+					rv = append(rv, f)
+				}
+			}
+			return rv
+		},
+	}
+	aFavIDs = &cli.Argument{
+		Name:      "id",
+		Required:  true,
+		Remaining: true,
+		Help:      "one or more favorite ids",
+		CompletionHandler: func(prev string, cur string) []string {
+			c, err := config.New()
+			if err != nil {
+				return nil
+			}
+
+			favs, err := favorites.New(c.GetFavoritesFile()).List()
+			if err != nil {
+				return nil
+			}
+
+			rv := []string{}
+			for _, f := range favs {
+				if id := strconv.Itoa(f.ID); strings.HasPrefix(id, cur) {
+					rv = append(rv, id)
+				}
+			}
+			return rv
+		},
+	}
+
+	cFavList = &cli.Cli{
+		Name: "list",
+		Help: "list favorites",
+	}
+	cFavRemove = &cli.Cli{
+		Name: "remove",
+		Help: "remove favorites",
+		Arguments: []*cli.Argument{
+			aFavIDs,
+		},
+	}
+	cFavExport = &cli.Cli{
+		Name: "export",
+		Help: "export favorites to standard output",
+		Options: []cli.Option{
+			oFavFormat,
+		},
+	}
+	cFav = &cli.Cli{
+		Name: "fav",
+		Help: "manage favorites",
+		Commands: []*cli.Cli{
+			cFavList,
+			cFavRemove,
+			cFavExport,
+		},
+	}
+)
+
+func isFavCommand(cmd *cli.Cli) bool {
+	return cmd == cFav || slices.Contains(cFav.Commands, cmd)
+}
+
+func formatPosition(pos float64) string {
+	if pos <= 0 {
+		return "-"
+	}
+	return time.Duration(pos * float64(time.Second)).Round(time.Second).String()
+}
+
+func favCommand(cmd *cli.Cli) {
+	if cmd == cFav {
+		cmd.Usage(false, "command required")
+		cleanup.Exit(1)
+	}
+
+	conf, err := config.New()
+	cleanup.Check(err)
+
+	store := favorites.New(conf.GetFavoritesFile())
+
+	switch cmd {
+	case cFavList:
+		favs, err := store.List()
+		cleanup.Check(err)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tSOURCE\tTABLE\tPOSITION\tTITLE")
+		for _, f := range favs {
+			table := f.Table
+			if table == "" {
+				table = "-"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", f.ID, f.Source, table, formatPosition(f.Position), f.Title)
+		}
+		cleanup.Check(w.Flush())
+
+	case cFavRemove:
+		ids := []int{}
+		for _, v := range aFavIDs.GetValues() {
+			id, err := strconv.Atoi(v)
+			if err != nil {
+				cleanup.Check(fmt.Errorf("invalid favorite id: %s", v))
+			}
+			ids = append(ids, id)
+		}
+		cleanup.Check(store.Remove(ids...))
+
+	case cFavExport:
+		favs, err := store.List()
+		cleanup.Check(err)
+
+		switch oFavFormat.GetValue() {
+		case "json":
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			cleanup.Check(enc.Encode(favs))
+
+		case "m3u":
+			fmt.Println("#EXTM3U")
+			for _, f := range favs {
+				fmt.Printf("#EXTINF:-1,%s\n", f.Title)
+				if f.Position > 0 {
+					fmt.Printf("#EXTVLCOPT:start-time=%.3f\n", f.Position)
+				}
+				fmt.Println(f.File)
+			}
+
+		default:
+			cleanup.Check(fmt.Errorf("invalid export format: %s", oFavFormat.GetValue()))
+		}
+	}
+}
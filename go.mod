@@ -5,8 +5,10 @@ go 1.24
 require (
 	github.com/danwakefield/fnmatch v0.0.0-20160403171240-cbb64ac3d964
 	github.com/goccy/go-yaml v1.18.0
+	github.com/godbus/dbus/v5 v5.2.2
 	github.com/google/shlex v0.0.0-20191202100458-e7afc7fbc510
 	github.com/jameycribbs/hare v0.6.0
+	golang.org/x/sys v0.27.0
 	google.golang.org/protobuf v1.36.10
 	gopkg.in/natefinch/npipe.v2 v2.0.0-20160621034901-c1b8fa8bdcce
 	rafaelmartins.com/p/octokeyz v0.0.0-20250628235640-5c90ed44ee72
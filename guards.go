@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/rafaelmartins/b8r/internal/cleanup"
+	"github.com/rafaelmartins/b8r/internal/config"
+	"github.com/rafaelmartins/b8r/internal/guard"
+	"github.com/rafaelmartins/b8r/internal/handlers"
+)
+
+func addGuards(conf *config.Config) {
+	for _, c := range conf.Guards {
+		var (
+			g       guard.Guard
+			err     error
+			muting  bool
+			pausing bool
+		)
+
+		switch c.Type {
+		case "mpris":
+			g, err = guard.NewMpris(c.Player)
+			pausing = true
+		case "pulseaudio":
+			g, err = guard.NewPulseAudio(c.Sink)
+			muting = true
+		case "command":
+			g, err = guard.NewCommand(c.Start, c.Stop)
+			pausing = true
+		default:
+			err = fmt.Errorf("%w: %s", guard.ErrTypeNotSupported, c.Type)
+		}
+
+		// a missing player or audio server must not prevent playback
+		if err != nil {
+			log.Printf("error: guard %s: %s", c.Name, err)
+			continue
+		}
+		cleanup.Register(g)
+
+		if c.Mute != nil {
+			muting = *c.Mute
+		}
+		if c.Pause != nil {
+			pausing = *c.Pause
+		}
+
+		handlers.GuardAdd(c.Name, g, muting, pausing)
+	}
+}
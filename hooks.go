@@ -0,0 +1,46 @@
+package main
+
+import (
+	"log"
+
+	"github.com/rafaelmartins/b8r/internal/cleanup"
+	"github.com/rafaelmartins/b8r/internal/config"
+	"github.com/rafaelmartins/b8r/internal/handlers"
+	"github.com/rafaelmartins/b8r/internal/hooks"
+	"github.com/rafaelmartins/b8r/internal/source"
+)
+
+func openHooks(conf *config.Config, src *source.Source, table string) *hooks.Hooks {
+	rv := hooks.New()
+	for _, h := range conf.Hooks {
+		if err := rv.Add(h.Event, h.Command); err != nil {
+			log.Printf("error: %s", err)
+		}
+	}
+	cleanup.Register(rv)
+
+	hooksWatchSource(rv, src, table)
+	handlers.SetHooks(rv)
+	return rv
+}
+
+func hooksWatchSource(h *hooks.Hooks, src *source.Source, table string) {
+	if err := src.SetCallbacks(
+		func() {
+			h.Fire(&hooks.Payload{
+				Event:  hooks.EventTableExhausted,
+				Source: src.GetBackendName(),
+				Table:  table,
+			})
+		},
+		func() {
+			h.Fire(&hooks.Payload{
+				Event:  hooks.EventTableRefilled,
+				Source: src.GetBackendName(),
+				Table:  table,
+			})
+		},
+	); err != nil {
+		log.Printf("error: %s", err)
+	}
+}
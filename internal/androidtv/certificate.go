@@ -3,6 +3,7 @@ package androidtv
 import (
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
@@ -10,6 +11,7 @@ import (
 	"fmt"
 	"math/big"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -46,30 +48,34 @@ func CreateCertificate(path string) (*tls.Certificate, error) {
 	}
 	rv.Certificate = append(rv.Certificate, cert)
 
+	if err := writeCertificate(path, priv, cert); err != nil {
+		return nil, err
+	}
+	return rv, nil
+}
+
+func writeCertificate(path string, priv *rsa.PrivateKey, cert []byte) error {
 	fp, err := os.Create(path)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer fp.Close()
 
 	privv, err := x509.MarshalPKCS8PrivateKey(priv)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	if err := pem.Encode(fp, &pem.Block{
 		Type:  "PRIVATE KEY",
 		Bytes: privv,
 	}); err != nil {
-		return nil, err
+		return err
 	}
 
-	if err := pem.Encode(fp, &pem.Block{
+	return pem.Encode(fp, &pem.Block{
 		Type:  "CERTIFICATE",
 		Bytes: cert,
-	}); err != nil {
-		return nil, err
-	}
-	return rv, nil
+	})
 }
 
 func OpenCertificate(path string) (*tls.Certificate, error) {
@@ -101,3 +107,45 @@ func OpenCertificate(path string) (*tls.Certificate, error) {
 
 	return rv, nil
 }
+
+type CertificateInfo struct {
+	Subject     string
+	Fingerprint string
+	NotBefore   time.Time
+	NotAfter    time.Time
+}
+
+func GetCertificateInfo(cert *tls.Certificate) (*CertificateInfo, error) {
+	if cert == nil || len(cert.Certificate) == 0 {
+		return nil, fmt.Errorf("androidtv: certificate not found")
+	}
+
+	c, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("androidtv: failed to parse certificate: %w", err)
+	}
+
+	sum := sha256.Sum256(c.Raw)
+	fp := []string{}
+	for _, b := range sum {
+		fp = append(fp, fmt.Sprintf("%02X", b))
+	}
+
+	return &CertificateInfo{
+		Subject:     c.Subject.String(),
+		Fingerprint: strings.Join(fp, ":"),
+		NotBefore:   c.NotBefore,
+		NotAfter:    c.NotAfter,
+	}, nil
+}
+
+func (i *CertificateInfo) Status(now time.Time, warning time.Duration) string {
+	left := i.NotAfter.Sub(now)
+	if left <= 0 {
+		return "expired"
+	}
+	if left < warning {
+		return fmt.Sprintf("expires in %d days", int(left.Hours()/24))
+	}
+	return "valid"
+}
@@ -0,0 +1,123 @@
+package androidtv
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCertificateRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cert.pem")
+
+	created, err := CreateCertificate(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opened, err := OpenCertificate(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(opened.Certificate) != 1 || string(opened.Certificate[0]) != string(created.Certificate[0]) {
+		t.Fatal("certificate mismatch")
+	}
+	key, ok := opened.PrivateKey.(*rsa.PrivateKey)
+	if !ok || !key.Equal(created.PrivateKey) {
+		t.Fatal("private key mismatch")
+	}
+
+	// the pair must be usable for the tls client authentication done when pairing
+	if _, err := tls.LoadX509KeyPair(path, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCertificateInfo(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notBefore := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	notAfter := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{
+			CommonName: "b8r",
+		},
+		NotBefore: notBefore,
+		NotAfter:  notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "cert.pem")
+	if err := writeCertificate(path, priv, der); err != nil {
+		t.Fatal(err)
+	}
+	cert, err := OpenCertificate(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := GetCertificateInfo(cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sum := sha256.Sum256(der)
+	if fp := strings.ReplaceAll(info.Fingerprint, ":", ""); fp != fmt.Sprintf("%X", sum) {
+		t.Errorf("unexpected fingerprint: %s", info.Fingerprint)
+	}
+	if info.Subject != "CN=b8r" {
+		t.Errorf("unexpected subject: %s", info.Subject)
+	}
+	if !info.NotBefore.Equal(notBefore) || !info.NotAfter.Equal(notAfter) {
+		t.Errorf("unexpected validity: %s - %s", info.NotBefore, info.NotAfter)
+	}
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		expected string
+	}{
+		{"valid", notAfter.AddDate(0, -6, 0), "valid"},
+		{"warning", notAfter.Add(-10*24*time.Hour - time.Hour), "expires in 10 days"},
+		{"last-day", notAfter.Add(-time.Hour), "expires in 0 days"},
+		{"expired", notAfter, "expired"},
+		{"long-expired", notAfter.AddDate(1, 0, 0), "expired"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := info.Status(tt.now, 30*24*time.Hour); status != tt.expected {
+				t.Errorf("unexpected status: got %q, want %q", status, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCertificateInfoNotFound(t *testing.T) {
+	for _, cert := range []*tls.Certificate{nil, {}} {
+		if _, err := GetCertificateInfo(cert); err == nil {
+			t.Error("expected error")
+		}
+	}
+}
+
+func TestOpenCertificateNotFound(t *testing.T) {
+	if _, err := OpenCertificate(filepath.Join(t.TempDir(), "missing.pem")); err == nil {
+		t.Fatal("expected error")
+	}
+}
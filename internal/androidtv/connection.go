@@ -18,22 +18,57 @@ import (
 var (
 	ErrConnectionClosed        = errors.New("connection is closed")
 	ErrConnectionBadMessageLen = errors.New("message length must fit a byte")
+	ErrConnectionNotConnected  = errors.New("connection is not established")
+)
+
+type ConnectionState byte
+
+const (
+	ConnectionDisconnected ConnectionState = iota
+	ConnectionConnecting
+	ConnectionConnected
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case ConnectionDisconnected:
+		return "disconnected"
+	case ConnectionConnecting:
+		return "connecting"
+	case ConnectionConnected:
+		return "connected"
+	}
+	return "unknown"
+}
+
+const (
+	backoffMin = 500 * time.Millisecond
+	backoffMax = 30 * time.Second
+
+	// android-tv devices send ping requests every few seconds, if nothing arrives for
+	// this long the connection is most likely dead (e.g. device went to standby).
+	idleTimeout = 30 * time.Second
 )
 
 type connectionHandler interface {
 	alloc() proto.Message
 	handle(msg proto.Message) error
+	setConnectionState(state ConnectionState)
 }
 
 type connection struct {
-	m      sync.Mutex
-	addr   string
-	dialer *tls.Dialer
-	conn   *tls.Conn
-	closed bool
+	m         sync.Mutex
+	cmtx      sync.RWMutex
+	wmtx      sync.Mutex
+	addr      string
+	dialer    *tls.Dialer
+	conn      *tls.Conn
+	closed    bool
+	closeCh   chan struct{}
+	reconnect bool
 }
 
-func newConnection(addr string, cert *tls.Certificate) (*connection, error) {
+func newConnection(addr string, cert *tls.Certificate, reconnect bool) (*connection, error) {
 	rv := &connection{
 		addr: addr,
 		dialer: &tls.Dialer{
@@ -45,6 +80,13 @@ func newConnection(addr string, cert *tls.Certificate) (*connection, error) {
 				InsecureSkipVerify: true,
 			},
 		},
+		closeCh:   make(chan struct{}),
+		reconnect: reconnect,
+	}
+
+	// reconnecting connections are dialed by the listener, that retries until closed.
+	if reconnect {
+		return rv, nil
 	}
 
 	if err := rv.dial(); err != nil {
@@ -53,61 +95,107 @@ func newConnection(addr string, cert *tls.Certificate) (*connection, error) {
 	return rv, nil
 }
 
+func (c *connection) getConn() *tls.Conn {
+	c.cmtx.RLock()
+	defer c.cmtx.RUnlock()
+	return c.conn
+}
+
+func (c *connection) isClosed() bool {
+	c.cmtx.RLock()
+	defer c.cmtx.RUnlock()
+	return c.closed
+}
+
 func (c *connection) dial() error {
 	if !c.m.TryLock() {
 		return nil
 	}
 	defer c.m.Unlock()
 
+	c.cmtx.Lock()
 	if c.conn != nil {
 		c.conn.Close()
 		c.conn = nil
 	}
+	c.cmtx.Unlock()
 
 	conn, err := c.dialer.Dial("tcp", c.addr)
 	if err != nil {
 		return err
 	}
 	if tc, ok := conn.(*tls.Conn); ok {
+		c.cmtx.Lock()
 		c.conn = tc
+		c.cmtx.Unlock()
 		return nil
 	}
+	conn.Close()
 	return fmt.Errorf("androidtv: invalid connection")
 }
 
-func (c *connection) redial() error {
-	var err error
-	for range 5 {
-		err = c.dial()
-		if err == nil {
+func (c *connection) redial(ch connectionHandler) error {
+	if !c.reconnect {
+		var err error
+		for range 5 {
+			err = c.dial()
+			if err == nil {
+				return nil
+			}
+			time.Sleep(500 * time.Millisecond)
+		}
+		return err
+	}
+
+	delay := backoffMin
+	for !c.isClosed() {
+		ch.setConnectionState(ConnectionConnecting)
+		if err := c.dial(); err == nil {
 			return nil
 		}
-		time.Sleep(500 * time.Millisecond)
+		ch.setConnectionState(ConnectionDisconnected)
+
+		select {
+		case <-c.closeCh:
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, backoffMax)
 	}
-	return err
+	return nil
 }
 
 func (c *connection) Close() error {
+	c.cmtx.Lock()
 	if c.closed {
+		c.cmtx.Unlock()
 		return fmt.Errorf("androidtv: %w", ErrConnectionClosed)
 	}
-
 	c.closed = true
-	if c.conn != nil {
-		return c.conn.Close()
+	c.cmtx.Unlock()
+
+	close(c.closeCh)
+	if conn := c.getConn(); conn != nil {
+		return conn.Close()
 	}
 	return nil
 }
 
 func (c *connection) Listen(ch connectionHandler) error {
-	if c.closed {
+	if c.isClosed() {
 		return fmt.Errorf("androidtv: %w", ErrConnectionClosed)
 	}
 
+	if c.reconnect {
+		if err := c.redial(ch); err != nil {
+			return err
+		}
+	}
+
 	buf := make([]byte, 256)
 
+messages:
 	for {
-		if c.closed {
+		if c.isClosed() {
 			return nil
 		}
 
@@ -115,20 +203,41 @@ func (c *connection) Listen(ch connectionHandler) error {
 		toRead := 0
 
 		for {
-			if c.closed {
+			if c.isClosed() {
 				return nil
 			}
 
-			n, err := c.conn.Read(buf)
+			var (
+				n   int
+				err error
+			)
+			if conn := c.getConn(); conn != nil {
+				if c.reconnect {
+					conn.SetReadDeadline(time.Now().Add(idleTimeout))
+				}
+				n, err = conn.Read(buf)
+			} else {
+				err = ErrConnectionNotConnected
+			}
 			if err != nil {
+				if c.isClosed() {
+					return nil
+				}
+				if c.reconnect {
+					ch.setConnectionState(ConnectionDisconnected)
+					if err := c.redial(ch); err != nil {
+						return err
+					}
+					continue messages
+				}
 				if err == io.EOF {
 					return nil
 				}
 				if errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET) {
-					if err := c.redial(); err != nil {
+					if err := c.redial(ch); err != nil {
 						return err
 					}
-					continue
+					continue messages
 				}
 				return err
 			}
@@ -165,7 +274,7 @@ func (c *connection) Listen(ch connectionHandler) error {
 }
 
 func (c *connection) Write(msg proto.Message) error {
-	if c.closed {
+	if c.isClosed() {
 		return fmt.Errorf("androidtv: %w", ErrConnectionClosed)
 	}
 
@@ -179,10 +288,19 @@ func (c *connection) Write(msg proto.Message) error {
 		return fmt.Errorf("androidtv: %w", ErrConnectionBadMessageLen)
 	}
 
-	if n, err := c.conn.Write([]byte{byte(l)}); err != nil {
+	c.wmtx.Lock()
+	defer c.wmtx.Unlock()
+
+	conn := c.getConn()
+	if conn == nil {
+		return fmt.Errorf("androidtv: %w", ErrConnectionNotConnected)
+	}
+
+	if n, err := conn.Write([]byte{byte(l)}); err != nil {
 		foundErr := true
-		if errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET) {
-			if err := c.redial(); err == nil {
+		if !c.reconnect && (errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET)) {
+			if err := c.redial(nil); err == nil {
+				conn = c.getConn()
 				foundErr = false
 			}
 		}
@@ -193,7 +311,7 @@ func (c *connection) Write(msg proto.Message) error {
 		return fmt.Errorf("androidtv: failed to send protobuf message length: failed to write")
 	}
 
-	if n, err := c.conn.Write(data); err != nil {
+	if n, err := conn.Write(data); err != nil {
 		return fmt.Errorf("androidtv: failed to send protobuf message: %w", err)
 	} else if n != l {
 		return fmt.Errorf("androidtv: failed to send protobuf message: failed to write")
@@ -222,7 +340,12 @@ func (c *connection) getClientPublicKey() (*rsa.PublicKey, error) {
 }
 
 func (c *connection) getServerPublicKey() (*rsa.PublicKey, error) {
-	pc := c.conn.ConnectionState().PeerCertificates
+	conn := c.getConn()
+	if conn == nil {
+		return nil, fmt.Errorf("androidtv: %w", ErrConnectionNotConnected)
+	}
+
+	pc := conn.ConnectionState().PeerCertificates
 	if len(pc) == 0 {
 		return nil, fmt.Errorf("androidtv: no server public key available")
 	}
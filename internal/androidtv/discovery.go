@@ -0,0 +1,340 @@
+package androidtv
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	mdnsService = "_androidtvremote2._tcp.local."
+
+	dnsTypeA   uint16 = 1
+	dnsTypePTR uint16 = 12
+	dnsTypeTXT uint16 = 16
+	dnsTypeSRV uint16 = 33
+	dnsClassIN uint16 = 1
+)
+
+var (
+	mdnsAddr = &net.UDPAddr{
+		IP:   net.IPv4(224, 0, 0, 251),
+		Port: 5353,
+	}
+
+	errDnsInvalidMessage = errors.New("androidtv: mdns: invalid message")
+)
+
+type DiscoveredDevice struct {
+	Name  string
+	Host  string
+	Port  uint16
+	Model string
+}
+
+type dnsRecord struct {
+	name  string
+	rtype uint16
+	data  []byte
+	msg   []byte
+	off   int
+}
+
+type discovery struct {
+	mtx       sync.Mutex
+	instances []string
+	srvs      map[string]*net.SRV
+	txts      map[string]map[string]string
+	addrs     map[string]net.IP
+	sources   map[string]net.IP
+}
+
+func dnsEncodeName(name string) []byte {
+	rv := []byte{}
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		rv = append(rv, byte(len(label)))
+		rv = append(rv, label...)
+	}
+	return append(rv, 0)
+}
+
+func dnsDecodeName(msg []byte, off int) (string, int, error) {
+	labels := []string{}
+	end := -1
+
+	// compression pointers may only point backwards, this also guards against loops
+	for jumps := 0; ; jumps++ {
+		if off >= len(msg) || jumps > len(msg) {
+			return "", 0, errDnsInvalidMessage
+		}
+
+		l := int(msg[off])
+		switch l & 0xc0 {
+		case 0x00:
+			if l == 0 {
+				if end < 0 {
+					end = off + 1
+				}
+				return strings.Join(labels, ".") + ".", end, nil
+			}
+			if off+1+l > len(msg) {
+				return "", 0, errDnsInvalidMessage
+			}
+			labels = append(labels, string(msg[off+1:off+1+l]))
+			off += 1 + l
+
+		case 0xc0:
+			if off+2 > len(msg) {
+				return "", 0, errDnsInvalidMessage
+			}
+			ptr := int(binary.BigEndian.Uint16(msg[off:]) & 0x3fff)
+			if ptr >= off {
+				return "", 0, errDnsInvalidMessage
+			}
+			if end < 0 {
+				end = off + 2
+			}
+			off = ptr
+
+		default:
+			return "", 0, errDnsInvalidMessage
+		}
+	}
+}
+
+func dnsQuery(name string, qtype uint16) []byte {
+	rv := make([]byte, 12)
+	binary.BigEndian.PutUint16(rv[4:], 1)
+	rv = append(rv, dnsEncodeName(name)...)
+	rv = binary.BigEndian.AppendUint16(rv, qtype)
+	return binary.BigEndian.AppendUint16(rv, dnsClassIN)
+}
+
+func dnsParse(msg []byte) ([]*dnsRecord, error) {
+	if len(msg) < 12 {
+		return nil, errDnsInvalidMessage
+	}
+
+	// queries are ignored, including the ones we send ourselves
+	if msg[2]&0x80 == 0 {
+		return nil, nil
+	}
+
+	qdcount := int(binary.BigEndian.Uint16(msg[4:]))
+	rrcount := int(binary.BigEndian.Uint16(msg[6:])) + int(binary.BigEndian.Uint16(msg[8:])) + int(binary.BigEndian.Uint16(msg[10:]))
+
+	off := 12
+	for range qdcount {
+		_, n, err := dnsDecodeName(msg, off)
+		if err != nil {
+			return nil, err
+		}
+		off = n + 4
+	}
+
+	rv := []*dnsRecord{}
+	for range rrcount {
+		name, n, err := dnsDecodeName(msg, off)
+		if err != nil {
+			return nil, err
+		}
+		if n+10 > len(msg) {
+			return nil, errDnsInvalidMessage
+		}
+
+		rtype := binary.BigEndian.Uint16(msg[n:])
+		rdlen := int(binary.BigEndian.Uint16(msg[n+8:]))
+		off = n + 10
+		if off+rdlen > len(msg) {
+			return nil, errDnsInvalidMessage
+		}
+
+		rv = append(rv, &dnsRecord{
+			name:  strings.ToLower(name),
+			rtype: rtype,
+			data:  msg[off : off+rdlen],
+			msg:   msg,
+			off:   off,
+		})
+		off += rdlen
+	}
+	return rv, nil
+}
+
+func (d *discovery) handle(msg []byte, src net.IP) error {
+	records, err := dnsParse(msg)
+	if err != nil {
+		return err
+	}
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	for _, rr := range records {
+		switch rr.rtype {
+		case dnsTypePTR:
+			if rr.name != mdnsService {
+				continue
+			}
+			instance, _, err := dnsDecodeName(rr.msg, rr.off)
+			if err != nil {
+				return err
+			}
+			if !slices.Contains(d.instances, instance) {
+				d.instances = append(d.instances, instance)
+			}
+			d.sources[instance] = src
+
+		case dnsTypeSRV:
+			if len(rr.data) < 7 {
+				return errDnsInvalidMessage
+			}
+			target, _, err := dnsDecodeName(rr.msg, rr.off+6)
+			if err != nil {
+				return err
+			}
+			d.srvs[rr.name] = &net.SRV{
+				Target:   strings.ToLower(target),
+				Port:     binary.BigEndian.Uint16(rr.data[4:]),
+				Priority: binary.BigEndian.Uint16(rr.data[0:]),
+				Weight:   binary.BigEndian.Uint16(rr.data[2:]),
+			}
+
+		case dnsTypeTXT:
+			txt := map[string]string{}
+			for data := rr.data; len(data) > 0; {
+				l := int(data[0])
+				if 1+l > len(data) {
+					return errDnsInvalidMessage
+				}
+				if k, v, found := strings.Cut(string(data[1:1+l]), "="); found {
+					txt[strings.ToLower(k)] = v
+				}
+				data = data[1+l:]
+			}
+			d.txts[rr.name] = txt
+
+		case dnsTypeA:
+			if len(rr.data) != 4 {
+				return errDnsInvalidMessage
+			}
+			d.addrs[rr.name] = net.IP(slices.Clone(rr.data))
+		}
+	}
+	return nil
+}
+
+func (d *discovery) devices() []*DiscoveredDevice {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	rv := []*DiscoveredDevice{}
+	for _, instance := range d.instances {
+		name, _, _ := strings.Cut(instance, ".")
+		dev := &DiscoveredDevice{
+			Name: name,
+			Port: 6466,
+		}
+
+		host := d.sources[instance]
+		if srv, found := d.srvs[strings.ToLower(instance)]; found {
+			dev.Port = srv.Port
+			if ip, found := d.addrs[srv.Target]; found {
+				host = ip
+			}
+		}
+		if host != nil {
+			dev.Host = host.String()
+		}
+
+		if txt, found := d.txts[strings.ToLower(instance)]; found {
+			for _, k := range []string{"md", "model"} {
+				if v, found := txt[k]; found && v != "" {
+					dev.Model = v
+					break
+				}
+			}
+		}
+		rv = append(rv, dev)
+	}
+
+	slices.SortFunc(rv, func(a *DiscoveredDevice, b *DiscoveredDevice) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return rv
+}
+
+func Discover(timeout time.Duration) ([]*DiscoveredDevice, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	return discover(mdnsAddr, ifaces, timeout)
+}
+
+func discover(addr *net.UDPAddr, ifaces []net.Interface, timeout time.Duration) ([]*DiscoveredDevice, error) {
+	conns := []*net.UDPConn{}
+	defer func() {
+		for _, c := range conns {
+			c.Close()
+		}
+	}()
+
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagMulticast == 0 {
+			continue
+		}
+		c, err := net.ListenMulticastUDP("udp4", &iface, addr)
+		if err != nil {
+			continue
+		}
+		conns = append(conns, c)
+	}
+	if len(conns) == 0 {
+		return nil, fmt.Errorf("androidtv: mdns: no multicast capable network interface found")
+	}
+
+	d := &discovery{
+		srvs:    map[string]*net.SRV{},
+		txts:    map[string]map[string]string{},
+		addrs:   map[string]net.IP{},
+		sources: map[string]net.IP{},
+	}
+
+	query := dnsQuery(mdnsService, dnsTypePTR)
+	deadline := time.Now().Add(timeout)
+
+	wg := sync.WaitGroup{}
+	for _, c := range conns {
+		if _, err := c.WriteToUDP(query, addr); err != nil {
+			continue
+		}
+		if err := c.SetReadDeadline(deadline); err != nil {
+			return nil, err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			buf := make([]byte, 9000)
+			for {
+				n, src, err := c.ReadFromUDP(buf)
+				if err != nil {
+					return
+				}
+
+				// malformed responses from other devices on the network are not our problem
+				d.handle(slices.Clone(buf[:n]), src.IP)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return d.devices(), nil
+}
@@ -0,0 +1,207 @@
+package androidtv
+
+import (
+	"encoding/binary"
+	"net"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func dnsAppendRecord(msg []byte, name []byte, rtype uint16, data []byte) []byte {
+	msg = append(msg, name...)
+	msg = binary.BigEndian.AppendUint16(msg, rtype)
+	msg = binary.BigEndian.AppendUint16(msg, dnsClassIN)
+	msg = binary.BigEndian.AppendUint32(msg, 120)
+	msg = binary.BigEndian.AppendUint16(msg, uint16(len(data)))
+	return append(msg, data...)
+}
+
+// dnsResponse builds the answer of an android-tv device, with the ptr record
+// pointing to the instance name using a compression pointer, like most
+// responders do.
+func dnsResponse(instance string, target string, port uint16, txt []string, ip net.IP) []byte {
+	rv := make([]byte, 12)
+	binary.BigEndian.PutUint16(rv[2:], 0x8400)
+
+	count := uint16(1)
+	ptrOff := len(rv) + len(dnsEncodeName(mdnsService)) + 10
+	rv = dnsAppendRecord(rv, dnsEncodeName(mdnsService), dnsTypePTR, dnsEncodeName(instance))
+	instanceName := binary.BigEndian.AppendUint16(nil, 0xc000|uint16(ptrOff))
+
+	if target != "" {
+		srv := binary.BigEndian.AppendUint16(nil, 0)
+		srv = binary.BigEndian.AppendUint16(srv, 0)
+		srv = binary.BigEndian.AppendUint16(srv, port)
+		rv = dnsAppendRecord(rv, instanceName, dnsTypeSRV, append(srv, dnsEncodeName(target)...))
+		count++
+	}
+
+	if len(txt) > 0 {
+		data := []byte{}
+		for _, t := range txt {
+			data = append(data, byte(len(t)))
+			data = append(data, t...)
+		}
+		rv = dnsAppendRecord(rv, instanceName, dnsTypeTXT, data)
+		count++
+	}
+
+	if ip != nil {
+		rv = dnsAppendRecord(rv, dnsEncodeName(target), dnsTypeA, ip.To4())
+		count++
+	}
+
+	binary.BigEndian.PutUint16(rv[6:], count)
+	return rv
+}
+
+// loopbackResponder answers every mdns query sent to a private multicast group
+// on the loopback interface.
+func loopbackResponder(t *testing.T, response []byte) (*net.UDPAddr, *net.Interface) {
+	t.Helper()
+
+	lo := (*net.Interface)(nil)
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 && iface.Flags&net.FlagMulticast != 0 && iface.Flags&net.FlagUp != 0 {
+			lo = &iface
+			break
+		}
+	}
+	if lo == nil {
+		t.Skip("no multicast capable loopback interface")
+	}
+
+	// borrow a free port, the group is private to the test anyway
+	l, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.LocalAddr().(*net.UDPAddr).Port
+	l.Close()
+
+	addr := &net.UDPAddr{
+		IP:   net.IPv4(239, 255, 66, 114),
+		Port: port,
+	}
+	c, err := net.ListenMulticastUDP("udp4", lo, addr)
+	if err != nil {
+		t.Skipf("failed to join multicast group on loopback: %s", err)
+	}
+	t.Cleanup(func() {
+		c.Close()
+	})
+
+	go func() {
+		buf := make([]byte, 9000)
+		for {
+			n, _, err := c.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			if n < 12 || buf[2]&0x80 != 0 {
+				continue
+			}
+			if _, err := c.WriteToUDP(response, addr); err != nil {
+				return
+			}
+		}
+	}()
+	return addr, lo
+}
+
+func TestDiscover(t *testing.T) {
+	tests := []struct {
+		name     string
+		response []byte
+		source   bool
+		expected []*DiscoveredDevice
+	}{
+		{
+			"full",
+			dnsResponse("Living Room."+mdnsService, "living-room.local.", 6467, []string{"bt=00:11:22:33:44:55", "md=Chromecast"}, net.IPv4(192, 168, 1, 10)),
+			false,
+			[]*DiscoveredDevice{
+				{
+					Name:  "Living Room",
+					Host:  "192.168.1.10",
+					Port:  6467,
+					Model: "Chromecast",
+				},
+			},
+		},
+		{
+			"ptr-only",
+			dnsResponse("Bedroom."+mdnsService, "", 0, nil, nil),
+			true,
+			[]*DiscoveredDevice{
+				{
+					Name: "Bedroom",
+					Port: 6466,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, lo := loopbackResponder(t, tt.response)
+
+			devices, err := discover(addr, []net.Interface{*lo}, time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// without a/srv records the host is whatever address the kernel picked to send the response
+			if tt.source {
+				for _, dev := range devices {
+					if net.ParseIP(dev.Host) == nil {
+						t.Fatalf("invalid source address: %q", dev.Host)
+					}
+					dev.Host = ""
+				}
+			}
+			if !reflect.DeepEqual(devices, tt.expected) {
+				for _, dev := range devices {
+					t.Logf("got: %+v", *dev)
+				}
+				t.Fatalf("unexpected devices")
+			}
+		})
+	}
+}
+
+func TestDiscoverNoInterface(t *testing.T) {
+	if _, err := discover(mdnsAddr, nil, time.Second); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestDnsParseInvalid(t *testing.T) {
+	valid := dnsResponse("Living Room."+mdnsService, "living-room.local.", 6466, nil, nil)
+
+	loop := slices.Clone(valid)
+	// the ptr record name points to itself
+	binary.BigEndian.PutUint16(loop[12:], 0xc000|12)
+
+	tests := []struct {
+		name string
+		msg  []byte
+	}{
+		{"short", valid[:8]},
+		{"truncated", valid[:len(valid)-3]},
+		{"pointer-loop", loop},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := dnsParse(tt.msg); err != errDnsInvalidMessage {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/sha256"
 	"crypto/tls"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
 
@@ -11,6 +12,18 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
+var ErrPairingCodeInvalid = errors.New("androidtv: invalid pairing code, must be 6 hexadecimal digits")
+
+func ValidatePairingCode(code string) error {
+	if len(code) != 6 {
+		return ErrPairingCodeInvalid
+	}
+	if _, err := hex.DecodeString(code); err != nil {
+		return ErrPairingCodeInvalid
+	}
+	return nil
+}
+
 type Pairing struct {
 	c          *connection
 	errCh      chan struct{}
@@ -22,7 +35,7 @@ type Pairing struct {
 }
 
 func NewPairing(host string, cert *tls.Certificate, dumpEvents bool) (*Pairing, error) {
-	c, err := newConnection(host+":6467", cert)
+	c, err := newConnection(host+":6467", cert, false)
 	if err != nil {
 		return nil, err
 	}
@@ -67,6 +80,8 @@ func (Pairing) alloc() proto.Message {
 	return &pb.PairingMessage{}
 }
 
+func (Pairing) setConnectionState(state ConnectionState) {}
+
 func (r *Pairing) handle(msg proto.Message) error {
 	pm := msg.(*pb.PairingMessage)
 
@@ -123,6 +138,9 @@ func (r *Pairing) handle(msg proto.Message) error {
 		if err != nil {
 			return err
 		}
+		if err := ValidatePairingCode(code); err != nil {
+			return err
+		}
 
 		cpk, err := r.c.getClientPublicKey()
 		if err != nil {
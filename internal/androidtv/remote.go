@@ -2,9 +2,13 @@ package androidtv
 
 import (
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/rafaelmartins/b8r/internal/androidtv/pb"
 	"google.golang.org/protobuf/proto"
@@ -18,6 +22,54 @@ const (
 	ppPause
 )
 
+type mStatus byte
+
+const (
+	mUnknown mStatus = iota
+	mMute
+	mUnmute
+)
+
+// ErrRemoteNotReady is returned by commands sent while the device is
+// disconnected or in standby.
+var ErrRemoteNotReady = errors.New("androidtv: device is not ready")
+
+type KeyDirection byte
+
+const (
+	KeyPress KeyDirection = iota + 1
+	KeyHold
+	KeyRelease
+)
+
+var keyDirections = map[KeyDirection]pb.RemoteDirection{
+	KeyPress:   pb.RemoteDirection_SHORT,
+	KeyHold:    pb.RemoteDirection_START_LONG,
+	KeyRelease: pb.RemoteDirection_END_LONG,
+}
+
+func KeyCodes() []string {
+	rv := []string{}
+	for k, v := range pb.RemoteKeyCode_value {
+		if v != int32(pb.RemoteKeyCode_KEYCODE_UNKNOWN) {
+			rv = append(rv, k)
+		}
+	}
+	slices.Sort(rv)
+	return rv
+}
+
+type State struct {
+	Connection  ConnectionState
+	PowerOn     bool
+	AppPackage  string
+	VolumeMax   uint32
+	VolumeLevel uint32
+	VolumeMuted bool
+}
+
+type StateHandler func(r *Remote, state State)
+
 type Remote struct {
 	c          *connection
 	errCh      chan struct{}
@@ -27,18 +79,36 @@ type Remote struct {
 	started    bool
 	ignore     bool
 
+	smtx      sync.Mutex
+	shandlers []StateHandler
+	conn      ConnectionState
+	restore   bool
+
+	appCh      chan struct{}
+	appKnown   bool
 	appPackage string
 
+	volCh    chan struct{}
+	volKnown bool
 	volMax   uint32
 	volLevel uint32
 	volMuted bool
 
+	// mute and play/pause intents, restored after (re)connecting
 	pp ppStatus
-	m  bool
+	m  mStatus
+
+	// changes made by us, reverted when closing
+	muted  bool
+	paused bool
 }
 
 func NewRemote(host string, cert *tls.Certificate, dumpEvents bool) (*Remote, error) {
-	c, err := newConnection(host+":6466", cert)
+	return newRemote(host+":6466", cert, dumpEvents)
+}
+
+func newRemote(addr string, cert *tls.Certificate, dumpEvents bool) (*Remote, error) {
+	c, err := newConnection(addr, cert, true)
 	if err != nil {
 		return nil, err
 	}
@@ -48,7 +118,13 @@ func NewRemote(host string, cert *tls.Certificate, dumpEvents bool) (*Remote, er
 		errCh:      make(chan struct{}),
 		dumpEvents: dumpEvents,
 		startedCh:  make(chan struct{}),
+		appCh:      make(chan struct{}),
+		volCh:      make(chan struct{}),
 		pp:         ppUnknown,
+		m:          mUnknown,
+
+		// intents set before the first connection are restored when it is established
+		restore: true,
 	}
 	go func() {
 		rv.err = rv.c.Listen(rv)
@@ -58,10 +134,15 @@ func NewRemote(host string, cert *tls.Certificate, dumpEvents bool) (*Remote, er
 }
 
 func (r *Remote) Close() error {
-	if r.m && r.volMuted {
+	r.smtx.Lock()
+	muted := r.muted
+	paused := r.paused
+	r.smtx.Unlock()
+
+	if muted {
 		r.Unmute()
 	}
-	if r.pp == ppPause {
+	if paused {
 		r.Play()
 	}
 
@@ -77,10 +158,25 @@ func (r *Remote) Write(msg proto.Message) error {
 	return r.c.Write(msg)
 }
 
-func (Remote) alloc() proto.Message {
+func (*Remote) alloc() proto.Message {
 	return &pb.RemoteMessage{}
 }
 
+func (r *Remote) setConnectionState(state ConnectionState) {
+	r.smtx.Lock()
+	if r.conn == state {
+		r.smtx.Unlock()
+		return
+	}
+	if r.started && state == ConnectionConnecting {
+		r.restore = true
+	}
+	r.conn = state
+	r.smtx.Unlock()
+
+	r.notify()
+}
+
 func (r *Remote) handle(msg proto.Message) error {
 	rm := msg.(*pb.RemoteMessage)
 
@@ -117,11 +213,18 @@ func (r *Remote) handle(msg proto.Message) error {
 	}
 
 	if rm.RemoteStart != nil {
+		// the device sends it again when powered on or off. the state must be
+		// connected before anyone waiting for the start is released.
+		r.smtx.Lock()
 		r.ignore = !rm.RemoteStart.Started
+		r.conn = ConnectionConnected
 		if !r.started {
 			close(r.startedCh)
 			r.started = true
 		}
+		r.smtx.Unlock()
+
+		r.notify()
 		return nil
 	}
 
@@ -134,88 +237,340 @@ func (r *Remote) handle(msg proto.Message) error {
 	}
 
 	if rm.RemoteImeKeyInject != nil {
+		r.smtx.Lock()
 		if rm.RemoteImeKeyInject.AppInfo != nil {
 			r.appPackage = rm.RemoteImeKeyInject.AppInfo.AppPackage
 		} else {
 			r.appPackage = ""
 		}
+		if !r.appKnown {
+			close(r.appCh)
+			r.appKnown = true
+		}
+		r.smtx.Unlock()
+
+		r.notify()
 		return nil
 	}
 
 	if rm.RemoteSetVolumeLevel != nil {
+		r.smtx.Lock()
 		r.volMax = rm.RemoteSetVolumeLevel.VolumeMax
 		r.volLevel = rm.RemoteSetVolumeLevel.VolumeLevel
 		r.volMuted = rm.RemoteSetVolumeLevel.VolumeMuted
+		if !r.volKnown {
+			close(r.volCh)
+			r.volKnown = true
+		}
+		restore := r.restore
+		r.restore = false
+		r.smtx.Unlock()
+
 		if r.volMax == 0 {
 			return fmt.Errorf("androidtv: can't control volume, please configure android-tv properly")
 		}
+
+		// the volume level is the last piece of state sent by the device after
+		// (re)connecting, it is safe to restore intents from now on.
+		if restore {
+			go r.restoreIntents()
+		}
+
+		r.notify()
 		return nil
 	}
 
 	return nil
 }
 
-func (r *Remote) SendKeyCode(code string) error {
-	<-r.startedCh
+func (r *Remote) restoreIntents() {
+	r.smtx.Lock()
+	m := r.m
+	pp := r.pp
+	r.smtx.Unlock()
+
+	if m == mMute {
+		if err := r.Mute(); err != nil {
+			fmt.Fprintf(os.Stderr, "error: androidtv: failed to restore mute: %s\n", err)
+		}
+	}
+	if pp == ppPause {
+		if err := r.Pause(); err != nil {
+			fmt.Fprintf(os.Stderr, "error: androidtv: failed to restore pause: %s\n", err)
+		}
+	}
+}
+
+func (r *Remote) State() State {
+	r.smtx.Lock()
+	defer r.smtx.Unlock()
+
+	return State{
+		Connection:  r.conn,
+		PowerOn:     r.started && !r.ignore,
+		AppPackage:  r.appPackage,
+		VolumeMax:   r.volMax,
+		VolumeLevel: r.volLevel,
+		VolumeMuted: r.volMuted,
+	}
+}
+
+func (r *Remote) WaitStarted(timeout time.Duration) error {
+	select {
+	case <-r.startedCh:
+		return nil
+	case <-r.errCh:
+		if r.err != nil {
+			return r.err
+		}
+		return fmt.Errorf("androidtv: %w", ErrConnectionClosed)
+	case <-time.After(timeout):
+		return fmt.Errorf("androidtv: %w: %s", ErrConnectionNotConnected, r.State().Connection)
+	}
+}
+
+func (r *Remote) WaitState(timeout time.Duration) (State, error) {
+	t := time.After(timeout)
+
+	select {
+	case <-r.volCh:
+	case <-t:
+		return r.State(), fmt.Errorf("androidtv: device did not report its state")
+	}
+
+	// app information is not mandatory, and may never be sent while on launcher
+	select {
+	case <-r.appCh:
+	case <-t:
+	}
+	return r.State(), nil
+}
+
+func (r *Remote) AddStateHandler(fn StateHandler) {
+	if fn == nil {
+		return
+	}
+
+	r.smtx.Lock()
+	defer r.smtx.Unlock()
+	r.shandlers = append(r.shandlers, fn)
+}
+
+func (r *Remote) notify() {
+	r.smtx.Lock()
+	handlers := slices.Clone(r.shandlers)
+	r.smtx.Unlock()
+
+	state := r.State()
+	for _, fn := range handlers {
+		fn(r, state)
+	}
+}
 
+func (r *Remote) ready() error {
+	r.smtx.Lock()
+	defer r.smtx.Unlock()
+
+	// avoid spamming commands while the device is not available for it
+	if !r.started || r.conn != ConnectionConnected {
+		return fmt.Errorf("%w: %s", ErrRemoteNotReady, r.conn)
+	}
 	if r.ignore {
-		return nil // avoid spamming commands while the device is not available for it
+		return fmt.Errorf("%w: standby", ErrRemoteNotReady)
 	}
+	return nil
+}
 
+// sendKey reports if the key was sent. guards don't fail while the device is
+// not ready, their intents are restored once it is.
+func (r *Remote) sendKey(code string, direction KeyDirection) (bool, error) {
+	err := r.SendKey(code, direction)
+	if errors.Is(err, ErrRemoteNotReady) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *Remote) SendKey(code string, direction KeyDirection) error {
 	keycode, found := pb.RemoteKeyCode_value[code]
 	if !found {
 		return fmt.Errorf("androidtv: key code not found: %s", code)
 	}
 
-	if err := r.Write(&pb.RemoteMessage{
+	dir, found := keyDirections[direction]
+	if !found {
+		return fmt.Errorf("androidtv: invalid key direction: %d", direction)
+	}
+
+	if err := r.ready(); err != nil {
+		return err
+	}
+
+	return r.Write(&pb.RemoteMessage{
 		RemoteKeyInject: &pb.RemoteKeyInject{
 			KeyCode:   pb.RemoteKeyCode(keycode),
-			Direction: pb.RemoteDirection_SHORT,
+			Direction: dir,
 		},
-	}); err != nil {
+	})
+}
+
+func (r *Remote) SendKeyCode(code string) error {
+	return r.SendKey(code, KeyPress)
+}
+
+func (r *Remote) LaunchApp(link string) error {
+	if link == "" {
+		return fmt.Errorf("androidtv: app link is required")
+	}
+
+	if err := r.ready(); err != nil {
+		return err
+	}
+
+	return r.Write(&pb.RemoteMessage{
+		RemoteAppLinkLaunchRequest: &pb.RemoteAppLinkLaunchRequest{
+			AppLink: link,
+		},
+	})
+}
+
+func (r *Remote) VolumeUp() error {
+	return r.SendKeyCode("KEYCODE_VOLUME_UP")
+}
+
+func (r *Remote) VolumeDown() error {
+	return r.SendKeyCode("KEYCODE_VOLUME_DOWN")
+}
+
+func (r *Remote) SetVolume(level uint32) error {
+	if err := r.ready(); err != nil {
+		return err
+	}
+
+	state, err := r.WaitState(5 * time.Second)
+	if err != nil {
 		return err
 	}
 
-	switch code {
-	case "KEYCODE_VOLUME_MUTE":
-		r.m = true
-	case "KEYCODE_MEDIA_PLAY":
-		r.pp = ppPlay
-	case "KEYCODE_MEDIA_PAUSE":
-		r.pp = ppPause
+	if level > state.VolumeMax {
+		return fmt.Errorf("androidtv: invalid volume level, must be between 0 and %d", state.VolumeMax)
 	}
 
+	// there's no known message to set the volume level directly, step there instead.
+	code := "KEYCODE_VOLUME_UP"
+	steps := int(level) - int(state.VolumeLevel)
+	if steps < 0 {
+		code = "KEYCODE_VOLUME_DOWN"
+		steps = -steps
+	}
+	for range steps {
+		if err := r.SendKeyCode(code); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 func (r *Remote) Mute() error {
-	if r.volMuted {
+	r.smtx.Lock()
+	r.m = mMute
+	muted := r.volMuted
+	r.smtx.Unlock()
+
+	// already muted by the user, not reverted when closing
+	if muted {
 		return nil
 	}
-	return r.SendKeyCode("KEYCODE_VOLUME_MUTE")
+
+	sent, err := r.sendKey("KEYCODE_VOLUME_MUTE", KeyPress)
+	if err != nil {
+		return err
+	}
+	if sent {
+		r.smtx.Lock()
+		r.muted = true
+		r.smtx.Unlock()
+	}
+	return nil
 }
 
 func (r *Remote) Unmute() error {
-	if !r.volMuted {
+	r.smtx.Lock()
+	r.m = mUnmute
+	mutedByUs := r.muted
+	muted := r.volMuted
+	if !muted {
+		r.muted = false
+	}
+	r.smtx.Unlock()
+
+	// devices muted by the user stay muted
+	if !mutedByUs || !muted {
 		return nil
 	}
-	return r.SendKeyCode("KEYCODE_VOLUME_MUTE")
+
+	sent, err := r.sendKey("KEYCODE_VOLUME_MUTE", KeyPress)
+	if err != nil {
+		return err
+	}
+	if sent {
+		r.smtx.Lock()
+		r.muted = false
+		r.smtx.Unlock()
+	}
+	return nil
 }
 
 func (r *Remote) isLauncher() bool {
-	return strings.HasPrefix(r.appPackage, "com.google.android.apps.tv.") || strings.HasPrefix(r.appPackage, "com.android.tv.")
+	app := r.State().AppPackage
+	return strings.HasPrefix(app, "com.google.android.apps.tv.") || strings.HasPrefix(app, "com.android.tv.")
 }
 
 func (r *Remote) Play() error {
-	if r.isLauncher() {
+	r.smtx.Lock()
+	r.pp = ppPlay
+	paused := r.paused
+	r.smtx.Unlock()
+
+	// only resume playback paused by us
+	if !paused {
 		return nil
 	}
-	return r.SendKeyCode("KEYCODE_MEDIA_PLAY")
+
+	// apps paused by us were left, there's nothing to resume
+	sent := true
+	if !r.isLauncher() {
+		var err error
+		sent, err = r.sendKey("KEYCODE_MEDIA_PLAY", KeyPress)
+		if err != nil {
+			return err
+		}
+	}
+	if sent {
+		r.smtx.Lock()
+		r.paused = false
+		r.smtx.Unlock()
+	}
+	return nil
 }
 
 func (r *Remote) Pause() error {
+	r.smtx.Lock()
+	r.pp = ppPause
+	r.smtx.Unlock()
+
 	if r.isLauncher() {
 		return nil
 	}
-	return r.SendKeyCode("KEYCODE_MEDIA_PAUSE")
+
+	sent, err := r.sendKey("KEYCODE_MEDIA_PAUSE", KeyPress)
+	if err != nil {
+		return err
+	}
+	if sent {
+		r.smtx.Lock()
+		r.paused = true
+		r.smtx.Unlock()
+	}
+	return nil
 }
@@ -0,0 +1,333 @@
+package androidtv
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rafaelmartins/b8r/internal/androidtv/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeDevice is a tls stand-in for an android-tv device, that hands accepted
+// connections to the test.
+type fakeDevice struct {
+	l     net.Listener
+	conns chan net.Conn
+}
+
+func newFakeDevice(t *testing.T) *fakeDevice {
+	t.Helper()
+
+	cert, err := CreateCertificate(filepath.Join(t.TempDir(), "device.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{*cert},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rv := &fakeDevice{
+		l:     l,
+		conns: make(chan net.Conn, 10),
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				close(rv.conns)
+				return
+			}
+			rv.conns <- conn
+		}
+	}()
+	t.Cleanup(func() {
+		l.Close()
+	})
+	return rv
+}
+
+func (d *fakeDevice) accept(t *testing.T) net.Conn {
+	t.Helper()
+
+	select {
+	case conn := <-d.conns:
+		t.Cleanup(func() {
+			conn.Close()
+		})
+		return conn
+	case <-time.After(5 * time.Second):
+		t.Fatal("remote did not connect")
+	}
+	return nil
+}
+
+func deviceSend(t *testing.T, conn net.Conn, msg *pb.RemoteMessage) {
+	t.Helper()
+
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Write(append([]byte{byte(len(data))}, data...)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func deviceStart(t *testing.T, conn net.Conn, app string, muted bool) {
+	t.Helper()
+
+	deviceSend(t, conn, &pb.RemoteMessage{
+		RemoteStart: &pb.RemoteStart{
+			Started: true,
+		},
+	})
+	deviceSend(t, conn, &pb.RemoteMessage{
+		RemoteImeKeyInject: &pb.RemoteImeKeyInject{
+			AppInfo: &pb.RemoteAppInfo{
+				AppPackage: app,
+			},
+		},
+	})
+	deviceSetMuted(t, conn, muted)
+}
+
+func deviceSetMuted(t *testing.T, conn net.Conn, muted bool) {
+	t.Helper()
+
+	deviceSend(t, conn, &pb.RemoteMessage{
+		RemoteSetVolumeLevel: &pb.RemoteSetVolumeLevel{
+			VolumeMax:   100,
+			VolumeLevel: 10,
+			VolumeMuted: muted,
+		},
+	})
+}
+
+// deviceReadKey returns the next key sent by the remote, or an empty string if
+// nothing arrives before the timeout.
+func deviceReadKey(t *testing.T, conn net.Conn, timeout time.Duration) string {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	for {
+		l := []byte{0}
+		if _, err := io.ReadFull(conn, l); err != nil {
+			// the remote may close the connection after sending everything
+			if errors.Is(err, os.ErrDeadlineExceeded) || errors.Is(err, io.EOF) {
+				return ""
+			}
+			t.Fatal(err)
+		}
+		data := make([]byte, l[0])
+		if _, err := io.ReadFull(conn, data); err != nil {
+			t.Fatal(err)
+		}
+
+		msg := &pb.RemoteMessage{}
+		if err := proto.Unmarshal(data, msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.RemoteKeyInject != nil {
+			return msg.RemoteKeyInject.KeyCode.String()
+		}
+	}
+}
+
+func newTestRemote(t *testing.T, d *fakeDevice) *Remote {
+	t.Helper()
+
+	cert, err := CreateCertificate(filepath.Join(t.TempDir(), "remote.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rv, err := newRemote(d.l.Addr().String(), cert, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go rv.Listen()
+	return rv
+}
+
+func waitMuted(t *testing.T, r *Remote, muted bool) {
+	t.Helper()
+
+	for range 100 {
+		if state := r.State(); state.VolumeMax > 0 && state.VolumeMuted == muted {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("remote did not report muted=%t", muted)
+}
+
+func TestRemoteRestoresIntents(t *testing.T) {
+	d := newFakeDevice(t)
+	r := newTestRemote(t, d)
+
+	// set before the first connection, must be replayed once connected
+	if err := r.Mute(); err != nil {
+		t.Fatal(err)
+	}
+
+	conn := d.accept(t)
+	deviceStart(t, conn, "com.example.player", false)
+	if key := deviceReadKey(t, conn, 5*time.Second); key != "KEYCODE_VOLUME_MUTE" {
+		t.Fatalf("unexpected key after connecting: %q", key)
+	}
+	deviceSetMuted(t, conn, true)
+	waitMuted(t, r, true)
+
+	// the device lost the mute while disconnected, it must be restored after reconnecting
+	conn.Close()
+	conn = d.accept(t)
+	deviceStart(t, conn, "com.example.player", false)
+	if key := deviceReadKey(t, conn, 5*time.Second); key != "KEYCODE_VOLUME_MUTE" {
+		t.Fatalf("unexpected key after reconnecting: %q", key)
+	}
+	deviceSetMuted(t, conn, true)
+	waitMuted(t, r, true)
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if key := deviceReadKey(t, conn, 5*time.Second); key != "KEYCODE_VOLUME_MUTE" {
+		t.Fatalf("mute not reverted when closing: %q", key)
+	}
+}
+
+func TestRemoteKeepsUserChanges(t *testing.T) {
+	d := newFakeDevice(t)
+	r := newTestRemote(t, d)
+
+	conn := d.accept(t)
+	deviceStart(t, conn, "com.example.player", true)
+	waitMuted(t, r, true)
+
+	// muted by the user, nothing to send or revert
+	if err := r.Mute(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Unmute(); err != nil {
+		t.Fatal(err)
+	}
+
+	// not paused by us, nothing to resume
+	if err := r.Play(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if key := deviceReadKey(t, conn, 500*time.Millisecond); key != "" {
+		t.Fatalf("unexpected key: %q", key)
+	}
+}
+
+func TestRemotePausePlay(t *testing.T) {
+	d := newFakeDevice(t)
+	r := newTestRemote(t, d)
+
+	conn := d.accept(t)
+	deviceStart(t, conn, "com.example.player", false)
+	waitMuted(t, r, false)
+
+	tests := []struct {
+		name string
+		fn   func() error
+		key  string
+	}{
+		{"pause", r.Pause, "KEYCODE_MEDIA_PAUSE"},
+		{"play", r.Play, "KEYCODE_MEDIA_PLAY"},
+		{"play-again", r.Play, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.fn(); err != nil {
+				t.Fatal(err)
+			}
+			timeout := 5 * time.Second
+			if tt.key == "" {
+				timeout = 500 * time.Millisecond
+			}
+			if key := deviceReadKey(t, conn, timeout); key != tt.key {
+				t.Fatalf("unexpected key: got %q, want %q", key, tt.key)
+			}
+		})
+	}
+}
+
+func TestRemoteCommandsAfterStart(t *testing.T) {
+	d := newFakeDevice(t)
+	r := newTestRemote(t, d)
+
+	conn := d.accept(t)
+	deviceSend(t, conn, &pb.RemoteMessage{
+		RemoteStart: &pb.RemoteStart{
+			Started: true,
+		},
+	})
+
+	// commands sent right after the start must not be dropped
+	if err := r.WaitStarted(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SendKeyCode("KEYCODE_HOME"); err != nil {
+		t.Fatal(err)
+	}
+	if key := deviceReadKey(t, conn, 5*time.Second); key != "KEYCODE_HOME" {
+		t.Fatalf("unexpected key: %q", key)
+	}
+}
+
+func TestRemoteCommandsNotReady(t *testing.T) {
+	d := newFakeDevice(t)
+	r := newTestRemote(t, d)
+
+	conn := d.accept(t)
+	deviceSend(t, conn, &pb.RemoteMessage{
+		RemoteStart: &pb.RemoteStart{
+			Started: false,
+		},
+	})
+	if err := r.WaitStarted(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		fn   func() error
+	}{
+		{"key", func() error { return r.SendKeyCode("KEYCODE_HOME") }},
+		{"launch", func() error { return r.LaunchApp("https://www.youtube.com") }},
+		{"volume", func() error { return r.SetVolume(10) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.fn(); !errors.Is(err, ErrRemoteNotReady) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+
+	// guards are restored once the device is ready, they don't fail
+	if err := r.Mute(); err != nil {
+		t.Fatal(err)
+	}
+	if key := deviceReadKey(t, conn, 500*time.Millisecond); key != "" {
+		t.Fatalf("unexpected key: %q", key)
+	}
+}
@@ -158,13 +158,16 @@ func (a *Argument) IsSet() bool {
 }
 
 type Cli struct {
+	Name      string
 	Help      string
 	Version   string
 	Options   []Option
 	Arguments []*Argument
+	Commands  []*Cli
 	iOptions  []Option
 	oHelp     *BoolOption
 	oVersion  *BoolOption
+	argv      []string
 }
 
 func (c *Cli) init() {
@@ -199,6 +202,33 @@ func (c *Cli) getOption(name byte) Option {
 	return nil
 }
 
+func (c *Cli) getCommand(argv []string) (*Cli, []string) {
+	if len(argv) < 2 {
+		return c, argv
+	}
+
+	for _, cmd := range c.Commands {
+		if cmd != nil && cmd.Name == argv[1] {
+			if cmd.Version == "" {
+				cmd.Version = c.Version
+			}
+			return cmd.getCommand(append([]string{argv[0] + " " + cmd.Name}, argv[2:]...))
+		}
+	}
+	return c, argv
+}
+
+// IsCommand reports if name is dispatched as a command when given as first
+// argument, and therefore can't be used as a value for the first argument.
+func (c *Cli) IsCommand(name string) bool {
+	for _, cmd := range c.Commands {
+		if cmd != nil && cmd.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 func isSpace(r byte) bool {
 	switch r {
 	case ' ', '\t', '\r', '\n':
@@ -230,6 +260,20 @@ func (c *Cli) completion() {
 			compLine = cl
 		}
 	}
+
+	// the word being completed must not select a command, as it may still be incomplete.
+	if l, lc := len(args), len(compLine); l > 0 && lc > 0 {
+		cmd := c
+		if isSpace(compLine[lc-1]) {
+			cmd, args = c.getCommand(args)
+		} else {
+			var a []string
+			cmd, a = c.getCommand(args[:l-1])
+			args = append(a, args[l-1])
+		}
+		cmd.init()
+		c = cmd
+	}
 	c.parse(args)
 
 	cur := ""
@@ -281,6 +325,9 @@ func (c *Cli) completion() {
 					continue
 				}
 				if !a.isSet || a.GetValue() == cur {
+					if i == 0 {
+						comp = append(comp, c.completeCommands(cur)...)
+					}
 					if a.CompletionHandler != nil {
 						comp = append(comp, a.CompletionHandler(aPrev, cur)...)
 					}
@@ -292,6 +339,9 @@ func (c *Cli) completion() {
 				}
 				aPrev = a.GetValue()
 			}
+			if len(c.Arguments) == 0 {
+				comp = append(comp, c.completeCommands(cur)...)
+			}
 		}
 	}
 
@@ -302,6 +352,16 @@ func (c *Cli) completion() {
 	os.Exit(0)
 }
 
+func (c *Cli) completeCommands(cur string) []string {
+	rv := []string{}
+	for _, cmd := range c.Commands {
+		if cmd != nil && strings.HasPrefix(cmd.Name, cur) {
+			rv = append(rv, cmd.Name)
+		}
+	}
+	return rv
+}
+
 func (c *Cli) parseOpt(name byte, opt []string) (bool, error) {
 	op := c.getOption(name)
 	if op == nil || len(opt) == 0 {
@@ -363,7 +423,15 @@ func (c *Cli) parse(argv []string) error {
 			continue
 		}
 
-		if lArg := len(c.Arguments); iArg < lArg || c.Arguments[lArg-1].Remaining {
+		lArg := len(c.Arguments)
+		if lArg == 0 {
+			if len(c.Commands) > 0 {
+				return fmt.Errorf("%w: invalid command: %s", errValidation, arg)
+			}
+			return fmt.Errorf("%w: invalid argument: %s", errValidation, arg)
+		}
+
+		if iArg < lArg || c.Arguments[lArg-1].Remaining {
 			idx := iArg
 			if idx >= lArg {
 				idx = lArg - 1
@@ -386,28 +454,32 @@ func (c *Cli) parse(argv []string) error {
 	return nil
 }
 
-func (c *Cli) Parse() {
+func (c *Cli) Parse() *Cli {
 	c.completion()
 
-	err := c.parse(os.Args)
+	cmd, argv := c.getCommand(os.Args)
+	cmd.argv = argv
+
+	err := cmd.parse(argv)
 
 	if err == nil || errors.Is(err, errValidation) {
-		if c.oHelp.GetValue() {
-			c.usage(os.Stderr, os.Args, true, nil)
+		if cmd.oHelp.GetValue() {
+			cmd.usage(os.Stderr, argv, true, nil)
 			os.Exit(0)
 		}
 
-		if len(os.Args) > 0 && c.oVersion != nil && c.oVersion.GetValue() {
-			fmt.Fprintf(os.Stderr, "%s %s", filepath.Base(os.Args[0]), c.Version)
+		if len(argv) > 0 && cmd.oVersion != nil && cmd.oVersion.GetValue() {
+			fmt.Fprintf(os.Stderr, "%s %s", filepath.Base(argv[0]), cmd.Version)
 			fmt.Fprintln(os.Stderr)
 			os.Exit(0)
 		}
 	}
 
 	if err != nil {
-		c.usage(os.Stderr, os.Args, false, err)
+		cmd.usage(os.Stderr, argv, false, err)
 		os.Exit(1)
 	}
+	return cmd
 }
 
 func (c *Cli) optUsage(opt Option) string {
@@ -492,6 +564,10 @@ func (c *Cli) usage(w io.Writer, argv []string, full bool, err any) {
 		}
 	}
 
+	if len(c.Arguments) == 0 && len(c.Commands) > 0 {
+		fmt.Fprint(w, " COMMAND ...")
+	}
+
 	fmt.Fprintln(w)
 	fmt.Fprintf(w, "%*s - %s", titlePadding, " ", c.Help)
 	fmt.Fprintln(w)
@@ -500,14 +576,28 @@ func (c *Cli) usage(w io.Writer, argv []string, full bool, err any) {
 		return
 	}
 
-	fmt.Fprintln(w)
-	fmt.Fprintln(w, "arguments:")
-	for _, arg := range c.Arguments {
-		if arg == nil {
-			continue
+	if len(c.Commands) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "commands:")
+		for _, cmd := range c.Commands {
+			if cmd == nil {
+				continue
+			}
+			fmt.Fprintf(w, "    %-20s %s", cmd.Name, cmd.Help)
+			fmt.Fprintln(w)
 		}
-		fmt.Fprintf(w, "    %-20s %s", c.argUsage(arg), arg.Help)
+	}
+
+	if len(c.Arguments) > 0 {
 		fmt.Fprintln(w)
+		fmt.Fprintln(w, "arguments:")
+		for _, arg := range c.Arguments {
+			if arg == nil {
+				continue
+			}
+			fmt.Fprintf(w, "    %-20s %s", c.argUsage(arg), arg.Help)
+			fmt.Fprintln(w)
+		}
 	}
 
 	fmt.Fprintln(w)
@@ -519,5 +609,9 @@ func (c *Cli) usage(w io.Writer, argv []string, full bool, err any) {
 }
 
 func (c *Cli) Usage(full bool, err any) {
-	c.usage(os.Stderr, os.Args, full, err)
+	argv := c.argv
+	if argv == nil {
+		argv = os.Args
+	}
+	c.usage(os.Stderr, argv, full, err)
 }
@@ -5,39 +5,113 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 
 	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/parser"
 )
 
 type Preset struct {
-	Name      string   `yaml:"name"`
-	Source    string   `yaml:"source"`
-	Include   *string  `yaml:"include"`
-	Exclude   *string  `yaml:"exclude"`
-	Entries   []string `yaml:"entries"`
-	Mute      *bool    `yaml:"mute"`
-	Random    *bool    `yaml:"random"`
-	Recursive *bool    `yaml:"recursive"`
-	Start     *bool    `yaml:"start"`
+	Name       string   `yaml:"name"`
+	Source     string   `yaml:"source"`
+	Include    *string  `yaml:"include"`
+	Exclude    *string  `yaml:"exclude"`
+	Entries    []string `yaml:"entries"`
+	Mute       *bool    `yaml:"mute"`
+	Random     *bool    `yaml:"random"`
+	Recursive  *bool    `yaml:"recursive"`
+	Start      *bool    `yaml:"start"`
+	AndroidTv  []string `yaml:"android-tv"`
+	Alang      string   `yaml:"alang"`
+	Slang      string   `yaml:"slang"`
+	AutoOrient *bool    `yaml:"auto-orient"`
+	Fit        string   `yaml:"fit"`
+	FitAlign   string   `yaml:"fit-align"`
+}
+
+type AndroidTv struct {
+	Name        string `yaml:"name"`
+	Host        string `yaml:"host"`
+	Certificate string `yaml:"certificate"`
+	Mute        bool   `yaml:"mute"`
+	Pause       bool   `yaml:"pause"`
+}
+
+type Guard struct {
+	Name   string `yaml:"name"`
+	Type   string `yaml:"type"`
+	Player string `yaml:"player"`
+	Sink   string `yaml:"sink"`
+	Start  string `yaml:"start"`
+	Stop   string `yaml:"stop"`
+	Mute   *bool  `yaml:"mute"`
+	Pause  *bool  `yaml:"pause"`
+}
+
+type Hook struct {
+	Event   string `yaml:"event"`
+	Command string `yaml:"command"`
+}
+
+type Binding struct {
+	Button int    `yaml:"button"`
+	Press  string `yaml:"press"`
+	Action string `yaml:"action"`
+}
+
+type DisplayLine struct {
+	Text    string `yaml:"text"`
+	Right   string `yaml:"right"`
+	Align   string `yaml:"align"`
+	Marquee bool   `yaml:"marquee"`
+}
+
+type DisplayPage struct {
+	Name  string         `yaml:"name"`
+	Lines []*DisplayLine `yaml:"lines"`
 }
 
 type Config struct {
 	AndroidTv struct {
-		Host string `yaml:"host"`
+		Host    string       `yaml:"host"`
+		Devices []*AndroidTv `yaml:"devices"`
 	} `yaml:"android-tv"`
 
 	Standalone struct {
 		SerialNumber string `yaml:"serial-number"`
+		MpvSocket    string `yaml:"mpv-socket"`
 	} `yaml:"standalone"`
 
 	MpvPlugin struct {
 		SerialNumber string `yaml:"serial-number"`
 		AndroidTv    struct {
-			Mute  bool `yaml:"mute"`
-			Pause bool `yaml:"pause"`
+			Mute    *bool    `yaml:"mute"`
+			Pause   *bool    `yaml:"pause"`
+			Devices []string `yaml:"devices"`
 		} `yaml:"android-tv"`
 	} `yaml:"mpv-plugin"`
 
+	Guards []*Guard `yaml:"guards"`
+
+	Hooks []*Hook `yaml:"hooks"`
+
+	Bindings []*Binding `yaml:"bindings"`
+
+	Screenshots struct {
+		Directory string `yaml:"directory"`
+		Format    string `yaml:"format"`
+	} `yaml:"screenshots"`
+
+	Speed struct {
+		Ladder   []float64 `yaml:"ladder"`
+		Hold     float64   `yaml:"hold"`
+		Remember string    `yaml:"remember"`
+	} `yaml:"speed"`
+
+	Display struct {
+		Pages []*DisplayPage `yaml:"pages"`
+	} `yaml:"display"`
+
 	Presets []*Preset `yaml:"presets"`
 
 	dir string
@@ -78,6 +152,28 @@ func New() (*Config, error) {
 	if err := yaml.NewDecoder(f).Decode(rv); err != nil {
 		return nil, err
 	}
+
+	names := []string{}
+	for _, d := range rv.GetAndroidTvDevices() {
+		if d.Name == "" {
+			return nil, fmt.Errorf("config: android-tv device name is required")
+		}
+		if slices.Contains(names, d.Name) {
+			return nil, fmt.Errorf("config: android-tv device name is duplicated: %s", d.Name)
+		}
+		names = append(names, d.Name)
+	}
+
+	names = []string{}
+	for _, g := range rv.Guards {
+		if g.Name == "" {
+			return nil, fmt.Errorf("config: guard name is required")
+		}
+		if slices.Contains(names, g.Name) {
+			return nil, fmt.Errorf("config: guard name is duplicated: %s", g.Name)
+		}
+		names = append(names, g.Name)
+	}
 	return rv, nil
 }
 
@@ -98,12 +194,123 @@ func (c *Config) ListPresets() []string {
 	return rv
 }
 
-func (c *Config) GetAndroidTvCertificate() (string, bool) {
-	rv := filepath.Join(c.dir, "android-tv.pem")
+func (c *Config) GetAndroidTvDevices() []*AndroidTv {
+	rv := []*AndroidTv{}
+	if c.AndroidTv.Host != "" {
+		// legacy single device configuration
+		rv = append(rv, &AndroidTv{
+			Name:        "default",
+			Host:        c.AndroidTv.Host,
+			Certificate: "android-tv.pem",
+		})
+	}
+	return append(rv, c.AndroidTv.Devices...)
+}
+
+func (c *Config) GetAndroidTvDevice(name string) *AndroidTv {
+	for _, d := range c.GetAndroidTvDevices() {
+		if name == d.Name {
+			return d
+		}
+	}
+	return nil
+}
+
+func (c *Config) ListAndroidTvDevices() []string {
+	rv := []string{}
+	for _, d := range c.GetAndroidTvDevices() {
+		rv = append(rv, d.Name)
+	}
+	return rv
+}
+
+func (c *Config) GetAndroidTvCertificate(name string) (string, bool) {
+	fn := "android-tv-" + name + ".pem"
+	if d := c.GetAndroidTvDevice(name); d != nil && d.Certificate != "" {
+		fn = d.Certificate
+	}
+
+	rv := fn
+	if !filepath.IsAbs(rv) {
+		rv = filepath.Join(c.dir, fn)
+	}
 	_, err := os.Stat(rv)
 	return rv, !errors.Is(err, os.ErrNotExist)
 }
 
+func (c *Config) SetAndroidTvHost(name string, host string) error {
+	fn := filepath.Join(c.dir, "config.yml")
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		return err
+	}
+
+	// edit the document tree instead of re-encoding the configuration, to keep comments and formatting
+	f, err := parser.ParseBytes(data, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+
+	path, src := "", ""
+	d := c.GetAndroidTvDevice(name)
+	if idx := slices.Index(c.AndroidTv.Devices, d); idx >= 0 {
+		path = fmt.Sprintf("$.android-tv.devices[%d]", idx)
+		src = fmt.Sprintf("host: %q\n", host)
+	} else if d != nil {
+		path = "$.android-tv"
+		src = fmt.Sprintf("host: %q\n", host)
+	} else if len(c.AndroidTv.Devices) > 0 {
+		path = "$.android-tv.devices"
+		src = fmt.Sprintf("- name: %q\n  host: %q\n", name, host)
+	} else if c.AndroidTv.Host != "" {
+		path = "$.android-tv"
+		src = fmt.Sprintf("devices:\n  - name: %q\n    host: %q\n", name, host)
+	} else {
+		path = "$"
+		src = fmt.Sprintf("android-tv:\n  devices:\n    - name: %q\n      host: %q\n", name, host)
+	}
+
+	if len(f.Docs) == 0 || f.Docs[0].Body == nil {
+		// empty config file, nothing to merge into
+		data = append(data, src...)
+	} else {
+		p, err := yaml.PathString(path)
+		if err != nil {
+			return err
+		}
+		sf, err := parser.ParseBytes([]byte(src), 0)
+		if err != nil {
+			return err
+		}
+		if err := p.MergeFromFile(f, sf); err != nil {
+			return fmt.Errorf("config: failed to update config file: %w", err)
+		}
+		data = []byte(f.String())
+	}
+
+	tmp := fn + ".tmp"
+	if err := os.WriteFile(tmp, data, 0666); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, fn); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if d != nil {
+		d.Host = host
+		if !slices.Contains(c.AndroidTv.Devices, d) {
+			c.AndroidTv.Host = host
+		}
+		return nil
+	}
+	c.AndroidTv.Devices = append(c.AndroidTv.Devices, &AndroidTv{
+		Name: name,
+		Host: host,
+	})
+	return nil
+}
+
 func (c *Config) GetTablesDirectory() (string, error) {
 	rv := filepath.Join(c.dir, "tables")
 	if err := os.MkdirAll(filepath.Dir(rv), 0777); err != nil {
@@ -111,3 +318,27 @@ func (c *Config) GetTablesDirectory() (string, error) {
 	}
 	return rv, nil
 }
+
+func (c *Config) GetPlayLogFile() string {
+	return filepath.Join(c.dir, "playlog.jsonl")
+}
+
+func (c *Config) GetStoreFile() string {
+	return filepath.Join(c.dir, "store.json")
+}
+
+func (c *Config) GetFavoritesFile() string {
+	return filepath.Join(c.dir, "favorites.json")
+}
+
+func (c *Config) GetScreenshotsDirectory() (string, error) {
+	if c.Screenshots.Directory != "" {
+		return c.Screenshots.Directory, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "Pictures", "b8r"), nil
+}
@@ -0,0 +1,165 @@
+package control
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/google/shlex"
+)
+
+var (
+	ErrCommandNotFound = errors.New("control: command not found")
+	ErrCommandInvalid  = errors.New("control: invalid command")
+)
+
+type Handler func(args []string) (string, error)
+
+type Server struct {
+	mtx      sync.RWMutex
+	l        net.Listener
+	handlers map[string]Handler
+	closeCh  chan struct{}
+}
+
+func New(socket string) (*Server, error) {
+	l, err := listen(socket)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{
+		l:        l,
+		handlers: map[string]Handler{},
+		closeCh:  make(chan struct{}),
+	}, nil
+}
+
+func (s *Server) Close() error {
+	select {
+	case <-s.closeCh:
+		return nil
+	default:
+	}
+	close(s.closeCh)
+	return s.l.Close()
+}
+
+func (s *Server) AddHandler(cmd string, fn Handler) {
+	if fn == nil {
+		return
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.handlers[cmd] = fn
+}
+
+func (s *Server) RemoveHandler(cmd string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	delete(s.handlers, cmd)
+}
+
+func (s *Server) call(line string) (string, error) {
+	argv, err := shlex.Split(line)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrCommandInvalid, err)
+	}
+	if len(argv) == 0 {
+		return "", ErrCommandInvalid
+	}
+
+	s.mtx.RLock()
+	fn, found := s.handlers[argv[0]]
+	s.mtx.RUnlock()
+	if !found {
+		return "", fmt.Errorf("%w: %s", ErrCommandNotFound, argv[0])
+	}
+	return fn(argv[1:])
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		// replies are a single line, prefixed by the status
+		reply := "ok"
+		if rv, err := s.call(line); err != nil {
+			reply = "error " + err.Error()
+		} else if rv != "" {
+			reply += " " + rv
+		}
+		if _, err := io.WriteString(conn, strings.ReplaceAll(reply, "\n", " ")+"\n"); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) Listen() error {
+	for {
+		conn, err := s.l.Accept()
+		if err != nil {
+			select {
+			case <-s.closeCh:
+				return nil
+			default:
+			}
+			log.Printf("error: control: %s", err)
+			continue
+		}
+		go s.serve(conn)
+	}
+}
+
+func Call(socket string, args ...string) (string, error) {
+	if len(args) == 0 {
+		return "", ErrCommandInvalid
+	}
+
+	conn, err := dial(socket)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	line := []string{}
+	for _, arg := range args {
+		line = append(line, quote(arg))
+	}
+	if _, err := io.WriteString(conn, strings.Join(line, " ")+"\n"); err != nil {
+		return "", err
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	reply = strings.TrimSuffix(reply, "\n")
+
+	if rv, found := strings.CutPrefix(reply, "error "); found {
+		return "", errors.New(rv)
+	}
+	if rv, found := strings.CutPrefix(reply, "ok"); found {
+		return strings.TrimPrefix(rv, " "), nil
+	}
+	return "", fmt.Errorf("control: invalid reply: %s", reply)
+}
+
+func quote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n\"'\\#") {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'"'"'`) + "'"
+}
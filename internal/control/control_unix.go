@@ -0,0 +1,16 @@
+//go:build unix
+// +build unix
+
+package control
+
+import (
+	"net"
+)
+
+func listen(socket string) (net.Listener, error) {
+	return net.Listen("unix", socket)
+}
+
+func dial(socket string) (net.Conn, error) {
+	return net.Dial("unix", socket)
+}
@@ -0,0 +1,15 @@
+package control
+
+import (
+	"net"
+
+	"gopkg.in/natefinch/npipe.v2"
+)
+
+func listen(socket string) (net.Listener, error) {
+	return npipe.Listen(socket)
+}
+
+func dial(socket string) (net.Conn, error) {
+	return npipe.Dial(socket)
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/rand"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"math/big"
 	"os"
 	"path/filepath"
@@ -14,13 +15,14 @@ import (
 	"github.com/jameycribbs/hare"
 	"github.com/jameycribbs/hare/datastores/disk"
 	"github.com/jameycribbs/hare/datastores/ram"
+	"github.com/rafaelmartins/b8r/internal/filelock"
 )
 
 var (
-	ErrEmpty                 = errors.New("dataset: empty")
-	ErrInvalidIndex          = errors.New("dataset: invalid index")
-	ErrInvalidCallback       = errors.New("dataset: invalid callback")
-	ErrLookAheadNotSupported = errors.New("dataset: lookahead not supported")
+	ErrEmpty           = errors.New("dataset: empty")
+	ErrInvalidIndex    = errors.New("dataset: invalid index")
+	ErrInvalidCallback = errors.New("dataset: invalid callback")
+	ErrNotFound        = errors.New("dataset: entry not found")
 )
 
 type entry struct {
@@ -41,6 +43,13 @@ func (e *entry) AfterFind(*hare.Database) error {
 	return nil
 }
 
+// entries enqueued to play next, and the entry picked in advance to look ahead.
+// tables store them in the queue directory, to survive restarts.
+type queue struct {
+	Entries []string `json:"entries"`
+	Next    string   `json:"next,omitempty"`
+}
+
 type metadata struct {
 	Source    string   `json:"source"`
 	Items     []string `json:"items"`
@@ -66,35 +75,98 @@ func TableExists(tableDir string, table string) bool {
 	return slices.Contains(ListTables(tableDir), table)
 }
 
-func TableSource(tableDir string, table string) (string, error) {
+func readMetadata(tableDir string, table string) (*metadata, error) {
 	fp, err := os.Open(filepath.Join(tableDir, "meta", table+".json"))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer fp.Close()
 
-	meta := &metadata{}
-	if err := json.NewDecoder(fp).Decode(meta); err != nil {
+	rv := &metadata{}
+	if err := json.NewDecoder(fp).Decode(rv); err != nil {
+		return nil, err
+	}
+	return rv, nil
+}
+
+func TableSource(tableDir string, table string) (string, error) {
+	meta, err := readMetadata(tableDir, table)
+	if err != nil {
 		return "", err
 	}
 	return meta.Source, nil
 }
 
+func TableItems(tableDir string, table string) ([]string, error) {
+	meta, err := readMetadata(tableDir, table)
+	if err != nil {
+		return nil, err
+	}
+	return meta.Items, nil
+}
+
+func queueFilename(tableDir string, table string) string {
+	return filepath.Join(tableDir, "queue", table+".json")
+}
+
+func readQueue(filename string) (*queue, error) {
+	fp, err := os.Open(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &queue{}, nil
+		}
+		return nil, err
+	}
+	defer fp.Close()
+
+	rv := &queue{}
+	if err := json.NewDecoder(fp).Decode(rv); err != nil {
+		return nil, err
+	}
+	return rv, nil
+}
+
+func writeQueue(filename string, q *queue) error {
+	dir := filepath.Dir(filename)
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+
+	// the queue may be read by other processes, e.g. when enqueuing from cli
+	tmp, err := os.CreateTemp(dir, ".queue-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := json.NewEncoder(tmp).Encode(q); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
 type DataSet struct {
-	mtx           sync.RWMutex
-	db            *hare.Database
-	source        string
-	table         string
-	next          string
-	items         []string
-	randomize     bool
-	withLookahead bool
+	mtx       sync.RWMutex
+	db        *hare.Database
+	source    string
+	table     string
+	queueFile string
+	queue     *queue
+	items     []string
+	randomize bool
+	exhausted func()
+	refilled  func()
 }
 
 func New(tableDir string, tableName string, tableCreate bool, source string, items []string, randomize bool) (*DataSet, error) {
 	rv := &DataSet{
 		source:    source,
 		table:     tableName,
+		queue:     &queue{},
 		randomize: randomize,
 	}
 	for _, item := range items {
@@ -114,14 +186,14 @@ func New(tableDir string, tableName string, tableCreate bool, source string, ite
 			return nil, err
 		}
 
-		rv.withLookahead = true
-
 		if err := rv.refill(); err != nil {
 			return nil, err
 		}
 		return rv, nil
 	}
 
+	rv.queueFile = queueFilename(tableDir, tableName)
+
 	dir := filepath.Join(tableDir, "data")
 	if err := os.MkdirAll(dir, 0777); err != nil {
 		return nil, err
@@ -161,6 +233,11 @@ func New(tableDir string, tableName string, tableCreate bool, source string, ite
 			return nil, err
 		}
 
+		if err := os.Remove(rv.queueFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			rv.db.Close()
+			return nil, err
+		}
+
 		if err := rv.refill(); err != nil {
 			return nil, err
 		}
@@ -186,6 +263,13 @@ func New(tableDir string, tableName string, tableCreate bool, source string, ite
 	return rv, nil
 }
 
+func (d *DataSet) SetCallbacks(exhausted func(), refilled func()) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	d.exhausted = exhausted
+	d.refilled = refilled
+}
+
 func (d *DataSet) Close() error {
 	return d.db.Close()
 }
@@ -239,6 +323,9 @@ func (d *DataSet) getIDs() ([]int, error) {
 		if err := d.refill(); err != nil {
 			return nil, err
 		}
+		if d.refilled != nil {
+			d.refilled()
+		}
 	}
 
 	tmp, err := d.db.IDs(d.table)
@@ -249,74 +336,316 @@ func (d *DataSet) getIDs() ([]int, error) {
 	return tmp, nil
 }
 
-func (d *DataSet) pick() (string, error) {
+// pick removes an entry from the table, and reports if it was the last one.
+func (d *DataSet) pick() (string, bool, error) {
 	tmp, err := d.getIDs()
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
 
 	idx := 0
 	if d.randomize {
 		bidx, err := rand.Int(rand.Reader, big.NewInt(int64(len(tmp))))
 		if err != nil {
-			return "", err
+			return "", false, err
 		}
 		idx = int(bidx.Int64())
 	}
 
 	v := entry{}
 	if err := d.db.Find(d.table, tmp[idx], &v); err != nil {
-		return "", err
+		return "", false, err
 	}
 
 	if err := d.db.Delete(d.table, v.ID); err != nil {
-		return "", err
+		return "", false, err
+	}
+	return v.Entry, len(tmp) == 1, nil
+}
+
+// played fires the exhausted callback when the last entry of the table is
+// handed out to be played, not when it is picked to look ahead.
+func (d *DataSet) played(last bool) {
+	if last && d.queue.Next == "" && d.exhausted != nil {
+		d.exhausted()
+	}
+}
+
+// loadQueue locks the queue file and reloads it. the returned function must be
+// called to release the lock, after saving any changes.
+func (d *DataSet) loadQueue() (func(), error) {
+	if d.queueFile == "" {
+		return func() {}, nil
 	}
 
-	return v.Entry, nil
+	// reloaded on every access, as it may be changed by other processes
+	l, err := filelock.New(d.queueFile)
+	if err != nil {
+		return nil, err
+	}
+	q, err := readQueue(d.queueFile)
+	if err != nil {
+		l.Unlock()
+		return nil, err
+	}
+	d.queue = q
+	return func() {
+		l.Unlock()
+	}, nil
+}
+
+func (d *DataSet) saveQueue() error {
+	if d.queueFile == "" {
+		return nil
+	}
+	return writeQueue(d.queueFile, d.queue)
+}
+
+// remove deletes an entry from the table, if found, and reports if it was the
+// last one.
+func (d *DataSet) remove(e string) (bool, error) {
+	if d.clen() == 0 {
+		return false, ErrNotFound
+	}
+
+	tmp, err := d.db.IDs(d.table)
+	if err != nil {
+		return false, err
+	}
+
+	for _, id := range tmp {
+		v := entry{}
+		if err := d.db.Find(d.table, id, &v); err != nil {
+			return false, err
+		}
+		if v.Entry != e {
+			continue
+		}
+
+		if err := d.db.Delete(d.table, id); err != nil {
+			return false, err
+		}
+		return len(tmp) == 1, nil
+	}
+	return false, ErrNotFound
 }
 
 func (d *DataSet) Next() (string, error) {
-	d.mtx.RLock()
-	defer d.mtx.RUnlock()
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
 
 	if len(d.items) == 0 {
 		return "", ErrEmpty
 	}
 
-	if d.next != "" {
-		rv := d.next
-		d.next = ""
+	unlock, err := d.loadQueue()
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	if len(d.queue.Entries) > 0 {
+		rv := d.queue.Entries[0]
+		d.queue.Entries = d.queue.Entries[1:]
+		wasNext := d.queue.Next == rv
+		if wasNext {
+			d.queue.Next = ""
+		}
+		if err := d.saveQueue(); err != nil {
+			return "", err
+		}
+
+		// enqueued entries are played once per cycle
+		last, err := d.remove(rv)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return "", err
+		}
+		d.played(last || (wasNext && d.clen() == 0))
 		return rv, nil
 	}
 
-	return d.pick()
+	if d.queue.Next != "" {
+		rv := d.queue.Next
+		d.queue.Next = ""
+		if err := d.saveQueue(); err != nil {
+			return "", err
+		}
+		d.played(d.clen() == 0)
+		return rv, nil
+	}
+
+	rv, last, err := d.pick()
+	if err != nil {
+		return "", err
+	}
+	d.played(last)
+	return rv, nil
 }
 
 func (d *DataSet) LookAhead() (string, error) {
-	d.mtx.RLock()
-	defer d.mtx.RUnlock()
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
 
 	if len(d.items) == 0 {
 		return "", ErrEmpty
 	}
 
-	if !d.withLookahead {
-		return "", ErrLookAheadNotSupported
+	unlock, err := d.loadQueue()
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	if len(d.queue.Entries) > 0 {
+		return d.queue.Entries[0], nil
+	}
+	if d.queue.Next != "" {
+		return d.queue.Next, nil
+	}
+
+	rv, _, err := d.pick()
+	if err != nil {
+		return "", err
+	}
+	d.queue.Next = rv
+	return rv, d.saveQueue()
+}
+
+// Enqueue adds entries to the queue, to be played before any other entry.
+func (d *DataSet) Enqueue(entries ...string) error {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	for _, e := range entries {
+		if !slices.Contains(d.items, e) {
+			return fmt.Errorf("%w: %s", ErrNotFound, e)
+		}
+	}
+
+	unlock, err := d.loadQueue()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	for _, e := range entries {
+		if !slices.Contains(d.queue.Entries, e) {
+			d.queue.Entries = append(d.queue.Entries, e)
+		}
+	}
+	return d.saveQueue()
+}
+
+// Queued returns the entries enqueued to play next.
+func (d *DataSet) Queued() ([]string, error) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	unlock, err := d.loadQueue()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return slices.Clone(d.queue.Entries), nil
+}
+
+// ClearQueue removes the enqueued entries. the entry picked to look ahead is
+// kept.
+func (d *DataSet) ClearQueue() error {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	unlock, err := d.loadQueue()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	d.queue.Entries = nil
+	return d.saveQueue()
+}
+
+// Remaining returns the entries not played yet, enqueued ones first, then in
+// table order. the table is not refilled when empty.
+func (d *DataSet) Remaining() ([]string, error) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	unlock, err := d.loadQueue()
+	if err != nil {
+		return nil, err
 	}
+	defer unlock()
 
-	if d.next != "" {
-		return d.next, nil
+	rv := slices.Clone(d.queue.Entries)
+	if d.queue.Next != "" && !slices.Contains(rv, d.queue.Next) {
+		rv = append(rv, d.queue.Next)
+	}
+	if d.clen() == 0 {
+		return rv, nil
 	}
 
-	rv, err := d.pick()
+	tmp, err := d.db.IDs(d.table)
 	if err != nil {
-		return "", nil
+		return nil, err
+	}
+	slices.Sort(tmp)
+
+	for _, id := range tmp {
+		v := entry{}
+		if err := d.db.Find(d.table, id, &v); err != nil {
+			return nil, err
+		}
+		if !slices.Contains(d.queue.Entries, v.Entry) {
+			rv = append(rv, v.Entry)
+		}
 	}
-	d.next = rv
 	return rv, nil
 }
 
+// Take removes an entry not played yet, as if it was picked.
+func (d *DataSet) Take(e string) error {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	unlock, err := d.loadQueue()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if i := slices.Index(d.queue.Entries, e); i >= 0 {
+		d.queue.Entries = slices.Delete(d.queue.Entries, i, i+1)
+		wasNext := d.queue.Next == e
+		if wasNext {
+			d.queue.Next = ""
+		}
+		if err := d.saveQueue(); err != nil {
+			return err
+		}
+		last, err := d.remove(e)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		d.played(last || (wasNext && d.clen() == 0))
+		return nil
+	}
+
+	if d.queue.Next != "" && d.queue.Next == e {
+		d.queue.Next = ""
+		if err := d.saveQueue(); err != nil {
+			return err
+		}
+		d.played(d.clen() == 0)
+		return nil
+	}
+
+	last, err := d.remove(e)
+	if err != nil {
+		return err
+	}
+	d.played(last)
+	return nil
+}
+
 func (d *DataSet) GetItems() []string {
 	d.mtx.Lock()
 	defer d.mtx.Unlock()
@@ -0,0 +1,186 @@
+package dataset
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// every dataset stands for a process using the table, like a session and the
+// queue command, changing the queue concurrently
+func TestDataSetConcurrentEnqueue(t *testing.T) {
+	dir := t.TempDir()
+
+	items := []string{}
+	for i := range 4 {
+		for j := range 10 {
+			items = append(items, fmt.Sprintf("item-%d-%d", i, j))
+		}
+	}
+
+	d, err := New(dir, "table", true, "local", items, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	wg := sync.WaitGroup{}
+	for i := range 4 {
+		ds, err := New(dir, "table", false, "local", nil, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ds.Close()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 10 {
+				if err := ds.Enqueue(fmt.Sprintf("item-%d-%d", i, j)); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	queued, err := d.Queued()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queued) != len(items) {
+		t.Fatalf("unexpected queue length: got %d, want %d", len(queued), len(items))
+	}
+	for _, item := range items {
+		if !slices.Contains(queued, item) {
+			t.Fatalf("%s lost", item)
+		}
+	}
+}
+
+func TestDataSetQueueShared(t *testing.T) {
+	dir := t.TempDir()
+
+	d1, err := New(dir, "table", true, "local", []string{"a", "b", "c"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d1.Close()
+
+	d2, err := New(dir, "table", false, "local", nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d2.Close()
+
+	next, err := d1.LookAhead()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next != "a" {
+		t.Fatalf("unexpected look ahead: %s", next)
+	}
+
+	if err := d2.Enqueue("c"); err != nil {
+		t.Fatal(err)
+	}
+	if next, err := d1.LookAhead(); err != nil || next != "c" {
+		t.Fatalf("enqueued entry not seen: %q %v", next, err)
+	}
+	if next, err := d1.Next(); err != nil || next != "c" {
+		t.Fatalf("unexpected next: %q %v", next, err)
+	}
+
+	if err := d2.ClearQueue(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the entry picked to look ahead is kept
+	if next, err := d1.Next(); err != nil || next != "a" {
+		t.Fatalf("unexpected next: %q %v", next, err)
+	}
+}
+
+func TestDataSetExhausted(t *testing.T) {
+	tests := []struct {
+		name    string
+		enqueue []string
+		// every step consumes an entry like a session does, looking ahead
+		// right after
+		step     func(d *DataSet) (string, error)
+		consumed []string
+	}{
+		{
+			"next",
+			nil,
+			(*DataSet).Next,
+			[]string{"a", "b", "c"},
+		},
+		{
+			"enqueued-first",
+			[]string{"c"},
+			(*DataSet).Next,
+			[]string{"c", "a", "b"},
+		},
+		{
+			"take",
+			nil,
+			func(d *DataSet) (string, error) {
+				next, err := d.LookAhead()
+				if err != nil {
+					return "", err
+				}
+				return next, d.Take(next)
+			},
+			[]string{"a", "b", "c"},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, table := range []string{"", "table"} {
+			t.Run(tt.name+"/"+table, func(t *testing.T) {
+				d, err := New(t.TempDir(), table, table != "", "local", []string{"a", "b", "c"}, false)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer d.Close()
+
+				exhausted := 0
+				d.SetCallbacks(func() { exhausted++ }, nil)
+
+				if len(tt.enqueue) > 0 {
+					if err := d.Enqueue(tt.enqueue...); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				for i, expected := range tt.consumed {
+					e, err := tt.step(d)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if e != expected {
+						t.Fatalf("unexpected entry %d: got %s, want %s", i, e, expected)
+					}
+
+					want := 0
+					if i == len(tt.consumed)-1 {
+						want = 1
+					}
+					if exhausted != want {
+						t.Fatalf("exhausted fired %d times after %s", exhausted, e)
+					}
+
+					if _, err := d.LookAhead(); err != nil {
+						t.Fatal(err)
+					}
+					if exhausted != want {
+						t.Fatalf("exhausted fired %d times when looking ahead after %s", exhausted, e)
+					}
+				}
+			})
+		}
+	}
+}
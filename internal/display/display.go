@@ -0,0 +1,329 @@
+package display
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rafaelmartins/b8r/internal/utils"
+	"rafaelmartins.com/p/octokeyz"
+)
+
+const (
+	marqueeRate  = 300 * time.Millisecond
+	marqueePause = 5
+	marqueeGap   = "   "
+)
+
+var reField = regexp.MustCompile(`\{([a-z0-9-]+)\}`)
+
+// Output is implemented by *octokeyz.Device and *Virtual.
+type Output interface {
+	DisplayLine(line octokeyz.DisplayLine, str string, align octokeyz.DisplayLineAlign) error
+	DisplayClearLine(line octokeyz.DisplayLine) error
+	GetDisplayCharsPerLine() byte
+}
+
+type Line struct {
+	Text    string
+	Right   string
+	Align   octokeyz.DisplayLineAlign
+	Marquee bool
+}
+
+type Page struct {
+	Name  string
+	Lines []*Line
+}
+
+type override struct {
+	text  string
+	align octokeyz.DisplayLineAlign
+}
+
+type rendered struct {
+	text  string
+	align octokeyz.DisplayLineAlign
+}
+
+type Layout struct {
+	mtx       sync.Mutex
+	out       Output
+	pages     []*Page
+	page      int
+	values    map[string]string
+	overrides map[octokeyz.DisplayLine]*override
+	written   map[octokeyz.DisplayLine]*rendered
+	marquee   map[octokeyz.DisplayLine]int
+	done      chan struct{}
+}
+
+func ParseAlign(align string) (octokeyz.DisplayLineAlign, error) {
+	switch align {
+	case "", "left":
+		return octokeyz.DisplayLineAlignLeft, nil
+	case "center":
+		return octokeyz.DisplayLineAlignCenter, nil
+	case "right":
+		return octokeyz.DisplayLineAlignRight, nil
+	}
+	return 0, fmt.Errorf("display: invalid alignment: %s", align)
+}
+
+func New(out Output, pages []*Page) (*Layout, error) {
+	if out == nil {
+		return nil, errors.New("display: missing output")
+	}
+	if len(pages) == 0 {
+		pages = DefaultPages()
+	}
+	for _, p := range pages {
+		if len(p.Lines) > int(octokeyz.DisplayLine8) {
+			return nil, fmt.Errorf("display: page %s: too many lines", p.Name)
+		}
+	}
+
+	rv := &Layout{
+		out:       out,
+		pages:     pages,
+		values:    map[string]string{},
+		overrides: map[octokeyz.DisplayLine]*override{},
+		written:   map[octokeyz.DisplayLine]*rendered{},
+		marquee:   map[octokeyz.DisplayLine]int{},
+		done:      make(chan struct{}),
+	}
+
+	go func() {
+		ticker := time.NewTicker(marqueeRate)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-rv.done:
+				return
+			case <-ticker.C:
+				rv.tick()
+			}
+		}
+	}()
+	return rv, nil
+}
+
+func (l *Layout) Close() error {
+	if l == nil {
+		return nil
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	select {
+	case <-l.done:
+	default:
+		close(l.done)
+	}
+	return nil
+}
+
+func (l *Layout) width() int {
+	return int(l.out.GetDisplayCharsPerLine())
+}
+
+// lines referencing only empty fields are not displayed at all, to avoid
+// leftover labels.
+func (l *Layout) expand(tmpl string) string {
+	fields := 0
+	empty := 0
+	rv := reField.ReplaceAllStringFunc(tmpl, func(s string) string {
+		fields++
+		v := l.values[s[1:len(s)-1]]
+		if v == "" {
+			empty++
+		}
+		return v
+	})
+	if fields > 0 && fields == empty {
+		return ""
+	}
+	return rv
+}
+
+func (l *Layout) render(line octokeyz.DisplayLine) (*rendered, bool) {
+	if o, found := l.overrides[line]; found {
+		return &rendered{text: o.text, align: o.align}, false
+	}
+
+	p := l.pages[l.page]
+	if int(line) > len(p.Lines) || p.Lines[line-1] == nil {
+		return &rendered{align: octokeyz.DisplayLineAlignLeft}, false
+	}
+	ln := p.Lines[line-1]
+
+	text := l.expand(ln.Text)
+	right := l.expand(ln.Right)
+	align := ln.Align
+	if align == 0 {
+		align = octokeyz.DisplayLineAlignLeft
+	}
+
+	// widths are in runes, values may be any utf-8 text, e.g. file names
+	w := l.width()
+	if right != "" && w > 0 {
+		tw := max(w-utf8.RuneCountInString(right)-1, 0)
+		if r := []rune(text); len(r) > tw {
+			text = string(r[:tw])
+		}
+		return &rendered{text: fmt.Sprintf("%-*s %s", tw, text, right), align: octokeyz.DisplayLineAlignLeft}, false
+	}
+
+	if ln.Marquee && w > 0 && utf8.RuneCountInString(text) > w {
+		s := []rune(text + marqueeGap)
+		pos := max(l.marquee[line]-marqueePause, 0) % len(s)
+		return &rendered{text: string(append(s, s...)[pos : pos+w]), align: octokeyz.DisplayLineAlignLeft}, true
+	}
+	return &rendered{text: text, align: align}, false
+}
+
+func (l *Layout) draw(line octokeyz.DisplayLine) (bool, error) {
+	r, scrolling := l.render(line)
+	if w, found := l.written[line]; found && *w == *r {
+		return scrolling, nil
+	}
+
+	var err error
+	if r.text == "" {
+		err = l.out.DisplayClearLine(line)
+	} else {
+		err = l.out.DisplayLine(line, r.text, r.align)
+	}
+	if err := utils.IgnoreDisplayMissing(err); err != nil {
+		return scrolling, err
+	}
+	l.written[line] = r
+	return scrolling, nil
+}
+
+func (l *Layout) drawAll() error {
+	for line := octokeyz.DisplayLine1; line <= octokeyz.DisplayLine8; line++ {
+		if _, err := l.draw(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *Layout) tick() {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	for line := octokeyz.DisplayLine1; line <= octokeyz.DisplayLine8; line++ {
+		scrolling, err := l.draw(line)
+		if err != nil {
+			return
+		}
+		if scrolling {
+			l.marquee[line]++
+		} else {
+			delete(l.marquee, line)
+		}
+	}
+}
+
+func (l *Layout) Set(field string, value string) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.values[field] == value {
+		return nil
+	}
+	l.values[field] = value
+
+	// scrolling restarts from the beginning when the content changes
+	for line := octokeyz.DisplayLine1; line <= octokeyz.DisplayLine8; line++ {
+		if int(line) <= len(l.pages[l.page].Lines) {
+			if ln := l.pages[l.page].Lines[line-1]; ln != nil && (strings.Contains(ln.Text, "{"+field+"}") || strings.Contains(ln.Right, "{"+field+"}")) {
+				delete(l.marquee, line)
+			}
+		}
+	}
+	return l.drawAll()
+}
+
+func (l *Layout) Get(field string) string {
+	if l == nil {
+		return ""
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.values[field]
+}
+
+// Override displays text in a line regardless of the current page, until
+// cleared.
+func (l *Layout) Override(line octokeyz.DisplayLine, text string, align octokeyz.DisplayLineAlign) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.overrides[line] = &override{
+		text:  text,
+		align: align,
+	}
+	_, err := l.draw(line)
+	return err
+}
+
+func (l *Layout) ClearOverride(line octokeyz.DisplayLine) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	delete(l.overrides, line)
+	_, err := l.draw(line)
+	return err
+}
+
+func (l *Layout) NextPage(step int) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.page = (l.page + len(l.pages) + step%len(l.pages)) % len(l.pages)
+	l.marquee = map[octokeyz.DisplayLine]int{}
+	return l.drawAll()
+}
+
+func (l *Layout) PageName() string {
+	if l == nil {
+		return ""
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.pages[l.page].Name
+}
+
+func (l *Layout) PageCount() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.pages)
+}
@@ -0,0 +1,303 @@
+package display
+
+import (
+	"strings"
+	"testing"
+
+	"rafaelmartins.com/p/octokeyz"
+)
+
+const testWidth = 16
+
+func screen(lines ...string) string {
+	border := "+" + strings.Repeat("-", testWidth) + "+\n"
+	rv := border
+	for i := range int(octokeyz.DisplayLine8) {
+		l := ""
+		if i < len(lines) {
+			l = lines[i]
+		}
+		rv += "|" + l + strings.Repeat(" ", testWidth-len([]rune(l))) + "|\n"
+	}
+	return rv + border
+}
+
+func set(values ...string) func(l *Layout) error {
+	return func(l *Layout) error {
+		for i := 0; i+1 < len(values); i += 2 {
+			if err := l.Set(values[i], values[i+1]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func ticks(n int) func(l *Layout) error {
+	return func(l *Layout) error {
+		for range n {
+			l.tick()
+		}
+		return nil
+	}
+}
+
+func nextPage(step int) func(l *Layout) error {
+	return func(l *Layout) error {
+		return l.NextPage(step)
+	}
+}
+
+func TestLayout(t *testing.T) {
+	pages := []*Page{
+		{
+			Name: "first",
+			Lines: []*Line{
+				{Text: "{title}", Align: octokeyz.DisplayLineAlignCenter},
+				{Text: "{guards}", Align: octokeyz.DisplayLineAlignRight},
+				{Text: "Source: {source}"},
+				{Text: "{a}-{b}"},
+				{Text: "{app}", Right: "{volume}"},
+				{Text: "C: {current}", Marquee: true},
+				nil,
+				{Text: "static"},
+			},
+		},
+		{
+			Name: "second",
+			Lines: []*Line{
+				{Text: "Table: {table}"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		ops      []func(l *Layout) error
+		expected string
+	}{
+		{
+			"empty",
+			nil,
+			screen("", "", "", "", "", "", "", "static"),
+		},
+		{
+			"fields",
+			[]func(l *Layout) error{
+				set("title", "b8r", "guards", "TV:on", "source", "local", "current", "cat.mp4"),
+			},
+			screen("      b8r       ", "           TV:on", "Source: local", "", "", "C: cat.mp4", "", "static"),
+		},
+		{
+			"partially-empty-fields",
+			[]func(l *Layout) error{
+				set("a", "x"),
+			},
+			screen("", "", "", "x-", "", "", "", "static"),
+		},
+		{
+			"cleared-field",
+			[]func(l *Layout) error{
+				set("source", "local"),
+				set("source", ""),
+			},
+			screen("", "", "", "", "", "", "", "static"),
+		},
+		{
+			"right",
+			[]func(l *Layout) error{
+				set("app", "youtube", "volume", "V:10"),
+			},
+			screen("", "", "", "", "youtube     V:10", "", "", "static"),
+		},
+		{
+			"right-truncated",
+			[]func(l *Layout) error{
+				set("app", "ñandu ñandu ñandu", "volume", "V:M"),
+			},
+			screen("", "", "", "", "ñandu ñandu  V:M", "", "", "static"),
+		},
+		{
+			"right-only",
+			[]func(l *Layout) error{
+				set("volume", "V:10"),
+			},
+			screen("", "", "", "", "            V:10", "", "", "static"),
+		},
+		{
+			"truncated",
+			[]func(l *Layout) error{
+				set("title", "a very long title"),
+			},
+			screen("a very long titl", "", "", "", "", "", "", "static"),
+		},
+		{
+			"marquee-pause",
+			[]func(l *Layout) error{
+				set("current", "abcdefghijklmnopq"),
+				ticks(marqueePause + 1),
+			},
+			screen("", "", "", "", "", "C: abcdefghijklm", "", "static"),
+		},
+		{
+			"marquee-step",
+			[]func(l *Layout) error{
+				set("current", "abcdefghijklmnopq"),
+				ticks(marqueePause + 4),
+			},
+			screen("", "", "", "", "", "abcdefghijklmnop", "", "static"),
+		},
+		{
+			"marquee-wrap",
+			[]func(l *Layout) error{
+				set("current", "abcdefghijklmnopq"),
+				ticks(marqueePause + 20),
+			},
+			screen("", "", "", "", "", "q   C: abcdefghi", "", "static"),
+		},
+		{
+			"marquee-utf8",
+			[]func(l *Layout) error{
+				set("current", "ação ação ação ação"),
+				ticks(marqueePause + 2),
+			},
+			screen("", "", "", "", "", ": ação ação ação", "", "static"),
+		},
+		{
+			"marquee-restart",
+			[]func(l *Layout) error{
+				set("current", "abcdefghijklmnopq"),
+				ticks(marqueePause + 3),
+				set("current", "bcdefghijklmnopqr"),
+			},
+			screen("", "", "", "", "", "C: bcdefghijklmn", "", "static"),
+		},
+		{
+			"marquee-short",
+			[]func(l *Layout) error{
+				set("current", "short"),
+				ticks(marqueePause + 3),
+			},
+			screen("", "", "", "", "", "C: short", "", "static"),
+		},
+		{
+			"page-next",
+			[]func(l *Layout) error{
+				set("title", "b8r", "table", "cats"),
+				nextPage(1),
+			},
+			screen("Table: cats"),
+		},
+		{
+			"page-wrap",
+			[]func(l *Layout) error{
+				set("title", "b8r", "table", "cats"),
+				nextPage(1),
+				nextPage(1),
+			},
+			screen("      b8r       ", "", "", "", "", "", "", "static"),
+		},
+		{
+			"page-prev",
+			[]func(l *Layout) error{
+				set("table", "cats"),
+				nextPage(-1),
+			},
+			screen("Table: cats"),
+		},
+		{
+			"page-values-kept",
+			[]func(l *Layout) error{
+				nextPage(1),
+				set("title", "b8r", "table", "cats"),
+				nextPage(1),
+			},
+			screen("      b8r       ", "", "", "", "", "", "", "static"),
+		},
+		{
+			"override",
+			[]func(l *Layout) error{
+				set("table", "cats"),
+				func(l *Layout) error {
+					return l.Override(octokeyz.DisplayLine8, "Preset: dogs", octokeyz.DisplayLineAlignLeft)
+				},
+				nextPage(1),
+			},
+			screen("Table: cats", "", "", "", "", "", "", "Preset: dogs"),
+		},
+		{
+			"override-cleared",
+			[]func(l *Layout) error{
+				func(l *Layout) error {
+					return l.Override(octokeyz.DisplayLine8, "Preset: dogs", octokeyz.DisplayLineAlignLeft)
+				},
+				func(l *Layout) error {
+					return l.ClearOverride(octokeyz.DisplayLine8)
+				},
+			},
+			screen("", "", "", "", "", "", "", "static"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewVirtual(testWidth)
+			l, err := New(v, pages)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the marquee is stepped by the test
+			l.Close()
+
+			if err := l.drawAll(); err != nil {
+				t.Fatal(err)
+			}
+			for _, op := range tt.ops {
+				if err := op(l); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if s := v.String(); s != tt.expected {
+				t.Fatalf("unexpected display:\n%swant:\n%s", s, tt.expected)
+			}
+		})
+	}
+}
+
+func TestVirtual(t *testing.T) {
+	tests := []struct {
+		name     string
+		str      string
+		align    octokeyz.DisplayLineAlign
+		expected string
+	}{
+		{"left", "abc", octokeyz.DisplayLineAlignLeft, "abc"},
+		{"right", "abc", octokeyz.DisplayLineAlignRight, "             abc"},
+		{"center", "abc", octokeyz.DisplayLineAlignCenter, "      abc       "},
+		{"truncated", "abcdefghijklmnopq", octokeyz.DisplayLineAlignLeft, "abcdefghijklmnop"},
+		{"utf8", "ação", octokeyz.DisplayLineAlignRight, "            ação"},
+		{"utf8-truncated", "ççççççççççççççççç", octokeyz.DisplayLineAlignLeft, "çççççççççççççççç"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewVirtual(testWidth)
+			if err := v.DisplayLine(octokeyz.DisplayLine1, tt.str, tt.align); err != nil {
+				t.Fatal(err)
+			}
+			if s := v.String(); s != screen(tt.expected) {
+				t.Fatalf("unexpected display:\n%swant:\n%s", s, screen(tt.expected))
+			}
+		})
+	}
+}
+
+func TestNewTooManyLines(t *testing.T) {
+	page := &Page{Name: "long"}
+	for range int(octokeyz.DisplayLine8) + 1 {
+		page.Lines = append(page.Lines, &Line{Text: "x"})
+	}
+	if _, err := New(NewVirtual(testWidth), []*Page{page}); err == nil {
+		t.Fatal("expected error")
+	}
+}
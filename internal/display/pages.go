@@ -0,0 +1,55 @@
+package display
+
+import (
+	"rafaelmartins.com/p/octokeyz"
+)
+
+func DefaultPages() []*Page {
+	title := &Line{Text: "{title}", Align: octokeyz.DisplayLineAlignCenter}
+	guards := &Line{Text: "{guards}", Align: octokeyz.DisplayLineAlignRight}
+	current := &Line{Text: "C: {current}", Marquee: true}
+	next := &Line{Text: "N: {next}", Marquee: true}
+	status := &Line{Text: "{status}"}
+
+	return []*Page{
+		{
+			Name: "playback",
+			Lines: []*Line{
+				title,
+				guards,
+				{Text: "Source: {source}"},
+				{Text: "{index}"},
+				{Text: "{atv-app}", Right: "{atv-volume}"},
+				current,
+				next,
+				status,
+			},
+		},
+		{
+			Name: "source",
+			Lines: []*Line{
+				title,
+				{Text: "Source: {source}"},
+				{Text: "Table: {table}"},
+				{Text: "{index}"},
+				nil,
+				current,
+				next,
+				status,
+			},
+		},
+		{
+			Name: "android-tv",
+			Lines: []*Line{
+				title,
+				guards,
+				{Text: "{atv-1}", Marquee: true},
+				{Text: "{atv-2}", Marquee: true},
+				{Text: "{atv-3}", Marquee: true},
+				{Text: "{atv-4}", Marquee: true},
+				nil,
+				status,
+			},
+		},
+	}
+}
@@ -0,0 +1,68 @@
+package display
+
+import (
+	"strings"
+	"sync"
+
+	"rafaelmartins.com/p/octokeyz"
+)
+
+// Virtual is an in-memory display, useful to preview layouts without a
+// macropad.
+type Virtual struct {
+	mtx   sync.Mutex
+	width int
+	lines [octokeyz.DisplayLine8]string
+}
+
+func NewVirtual(width int) *Virtual {
+	return &Virtual{
+		width: width,
+	}
+}
+
+func (v *Virtual) DisplayLine(line octokeyz.DisplayLine, str string, align octokeyz.DisplayLineAlign) error {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	r := []rune(str)
+	if len(r) > v.width {
+		r = r[:v.width]
+	}
+	str = string(r)
+
+	pad := v.width - len(r)
+	switch align {
+	case octokeyz.DisplayLineAlignRight:
+		str = strings.Repeat(" ", pad) + str
+	case octokeyz.DisplayLineAlignCenter:
+		str = strings.Repeat(" ", pad/2) + str + strings.Repeat(" ", pad-pad/2)
+	default:
+		str += strings.Repeat(" ", pad)
+	}
+	v.lines[line-1] = str
+	return nil
+}
+
+func (v *Virtual) DisplayClearLine(line octokeyz.DisplayLine) error {
+	return v.DisplayLine(line, "", octokeyz.DisplayLineAlignLeft)
+}
+
+func (v *Virtual) GetDisplayCharsPerLine() byte {
+	return byte(v.width)
+}
+
+func (v *Virtual) String() string {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	border := "+" + strings.Repeat("-", v.width) + "+\n"
+	rv := border
+	for _, l := range v.lines {
+		if l == "" {
+			l = strings.Repeat(" ", v.width)
+		}
+		rv += "|" + l + "|\n"
+	}
+	return rv + border
+}
@@ -0,0 +1,103 @@
+package exif
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+)
+
+var (
+	ErrNotFound = errors.New("exif: orientation not found")
+
+	errInvalid = errors.New("exif: invalid data")
+)
+
+const tagOrientation = 0x0112
+
+func orientationFromTiff(data []byte) (int, error) {
+	if len(data) < 8 {
+		return 0, errInvalid
+	}
+
+	var bo binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return 0, errInvalid
+	}
+	if bo.Uint16(data[2:]) != 42 {
+		return 0, errInvalid
+	}
+
+	off := int(bo.Uint32(data[4:]))
+	if off+2 > len(data) {
+		return 0, errInvalid
+	}
+
+	cnt := int(bo.Uint16(data[off:]))
+	off += 2
+	for i := 0; i < cnt; i++ {
+		e := off + i*12
+		if e+12 > len(data) {
+			return 0, errInvalid
+		}
+		if bo.Uint16(data[e:]) == tagOrientation {
+			return int(bo.Uint16(data[e+8:])), nil
+		}
+	}
+	return 0, ErrNotFound
+}
+
+// Orientation returns the EXIF orientation (1-8) of a JPEG file.
+func Orientation(file string) (int, error) {
+	fp, err := os.Open(file)
+	if err != nil {
+		return 0, err
+	}
+	defer fp.Close()
+
+	r := bufio.NewReader(fp)
+
+	soi := make([]byte, 2)
+	if _, err := io.ReadFull(r, soi); err != nil {
+		return 0, err
+	}
+	if soi[0] != 0xff || soi[1] != 0xd8 {
+		return 0, ErrNotFound
+	}
+
+	for {
+		hdr := make([]byte, 4)
+		if _, err := io.ReadFull(r, hdr); err != nil {
+			return 0, err
+		}
+		if hdr[0] != 0xff {
+			return 0, errInvalid
+		}
+
+		// image data starts at SOS, metadata comes before it
+		marker := hdr[1]
+		if marker == 0xda || marker == 0xd9 {
+			return 0, ErrNotFound
+		}
+
+		l := int(binary.BigEndian.Uint16(hdr[2:]))
+		if l < 2 {
+			return 0, errInvalid
+		}
+		data := make([]byte, l-2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return 0, err
+		}
+
+		if marker == 0xe1 && bytes.HasPrefix(data, []byte("Exif\x00\x00")) {
+			return orientationFromTiff(data[6:])
+		}
+	}
+}
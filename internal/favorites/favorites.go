@@ -0,0 +1,165 @@
+package favorites
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rafaelmartins/b8r/internal/filelock"
+)
+
+var ErrNotFound = errors.New("favorites: not found")
+
+type Favorite struct {
+	ID       int       `json:"id"`
+	Time     time.Time `json:"time"`
+	Source   string    `json:"source"`
+	Table    string    `json:"table,omitempty"`
+	Entry    string    `json:"entry"`
+	File     string    `json:"file"`
+	Title    string    `json:"title"`
+	Position float64   `json:"position,omitempty"`
+}
+
+type Store struct {
+	mtx  sync.Mutex
+	file string
+}
+
+func New(file string) *Store {
+	return &Store{
+		file: file,
+	}
+}
+
+// favorites are added and removed by the fav command while sessions run, the
+// file is never cached.
+func (s *Store) read() ([]*Favorite, error) {
+	data, err := os.ReadFile(s.file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []*Favorite{}, nil
+		}
+		return nil, fmt.Errorf("favorites: %w", err)
+	}
+
+	rv := []*Favorite{}
+	if err := json.Unmarshal(data, &rv); err != nil {
+		return nil, fmt.Errorf("favorites: %w", err)
+	}
+	return rv, nil
+}
+
+func (s *Store) write(favs []*Favorite) error {
+	data, err := json.MarshalIndent(favs, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.file), ".favorites-*")
+	if err != nil {
+		return fmt.Errorf("favorites: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("favorites: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("favorites: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.file); err != nil {
+		return fmt.Errorf("favorites: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) update(fn func(favs []*Favorite) ([]*Favorite, error)) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	l, err := filelock.New(s.file)
+	if err != nil {
+		return fmt.Errorf("favorites: %w", err)
+	}
+	defer l.Unlock()
+
+	favs, err := s.read()
+	if err != nil {
+		return err
+	}
+	favs, err = fn(favs)
+	if err != nil {
+		return err
+	}
+	return s.write(favs)
+}
+
+func (s *Store) List() ([]*Favorite, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.read()
+}
+
+func (s *Store) Get(id int) (*Favorite, error) {
+	favs, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range favs {
+		if f.ID == id {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %d", ErrNotFound, id)
+}
+
+// Add stores a new favorite, or updates the existing favorite for the same
+// source and entry.
+func (s *Store) Add(f *Favorite) (*Favorite, error) {
+	rv := *f
+	if rv.Time.IsZero() {
+		rv.Time = time.Now()
+	}
+
+	if err := s.update(func(favs []*Favorite) ([]*Favorite, error) {
+		for i, ff := range favs {
+			if ff.Source == rv.Source && ff.Entry == rv.Entry {
+				rv.ID = ff.ID
+				favs[i] = &rv
+				return favs, nil
+			}
+		}
+
+		rv.ID = 1
+		for _, ff := range favs {
+			rv.ID = max(rv.ID, ff.ID+1)
+		}
+		return append(favs, &rv), nil
+	}); err != nil {
+		return nil, err
+	}
+	return &rv, nil
+}
+
+func (s *Store) Remove(ids ...int) error {
+	return s.update(func(favs []*Favorite) ([]*Favorite, error) {
+		for _, id := range ids {
+			idx := slices.IndexFunc(favs, func(f *Favorite) bool {
+				return f.ID == id
+			})
+			if idx < 0 {
+				return nil, fmt.Errorf("%w: %d", ErrNotFound, id)
+			}
+			favs = slices.Delete(favs, idx, idx+1)
+		}
+		return favs, nil
+	})
+}
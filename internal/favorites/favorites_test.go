@@ -0,0 +1,100 @@
+package favorites
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// every store stands for a session or the fav command, adding favorites to the
+// same file concurrently
+func TestFavoritesConcurrentAdd(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "favorites.json")
+
+	wg := sync.WaitGroup{}
+	for i := range 4 {
+		s := New(file)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 10 {
+				if _, err := s.Add(&Favorite{Source: "local", Entry: fmt.Sprintf("entry-%d-%d", i, j)}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	favs, err := New(file).List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(favs) != 40 {
+		t.Fatalf("unexpected favorites count: %d", len(favs))
+	}
+
+	ids := []int{}
+	for _, f := range favs {
+		if slices.Contains(ids, f.ID) {
+			t.Fatalf("duplicated id: %d", f.ID)
+		}
+		ids = append(ids, f.ID)
+	}
+
+	// temporary files are not left behind
+	entries, err := os.ReadDir(filepath.Dir(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.Name() != "favorites.json" && e.Name() != "favorites.json.lock" {
+			t.Errorf("unexpected file: %s", e.Name())
+		}
+	}
+}
+
+func TestFavoritesAddRemove(t *testing.T) {
+	s := New(filepath.Join(t.TempDir(), "favorites.json"))
+
+	f1, err := s.Add(&Favorite{Source: "local", Entry: "a.mp4", Position: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f2, err := s.Add(&Favorite{Source: "local", Entry: "b.mp4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// same source and entry update the existing favorite
+	f3, err := s.Add(&Favorite{Source: "local", Entry: "a.mp4", Position: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f3.ID != f1.ID {
+		t.Fatalf("favorite not updated: %d != %d", f3.ID, f1.ID)
+	}
+	if f, err := s.Get(f1.ID); err != nil || f.Position != 20 {
+		t.Fatalf("unexpected favorite: %+v %v", f, err)
+	}
+
+	// nothing is removed if any of the ids is missing
+	if err := s.Remove(f2.ID, 100); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := s.Get(f2.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Remove(f1.ID, f2.ID); err != nil {
+		t.Fatal(err)
+	}
+	if favs, err := s.List(); err != nil || len(favs) != 0 {
+		t.Fatalf("favorites not removed: %v %v", favs, err)
+	}
+}
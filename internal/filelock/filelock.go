@@ -0,0 +1,40 @@
+package filelock
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Lock is an exclusive lock shared by processes. files replaced by renaming
+// can't be locked themselves, a lock file is created next to them instead.
+type Lock struct {
+	fp *os.File
+}
+
+func New(file string) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(file), 0777); err != nil {
+		return nil, fmt.Errorf("filelock: %w", err)
+	}
+
+	fp, err := os.OpenFile(file+".lock", os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, fmt.Errorf("filelock: %w", err)
+	}
+
+	if err := lock(fp); err != nil {
+		fp.Close()
+		return nil, fmt.Errorf("filelock: %w", err)
+	}
+	return &Lock{
+		fp: fp,
+	}, nil
+}
+
+func (l *Lock) Unlock() error {
+	if err := unlock(l.fp); err != nil {
+		l.fp.Close()
+		return fmt.Errorf("filelock: %w", err)
+	}
+	return l.fp.Close()
+}
@@ -0,0 +1,22 @@
+//go:build unix
+// +build unix
+
+package filelock
+
+import (
+	"os"
+	"syscall"
+)
+
+func lock(fp *os.File) error {
+	for {
+		err := syscall.Flock(int(fp.Fd()), syscall.LOCK_EX)
+		if err != syscall.EINTR {
+			return err
+		}
+	}
+}
+
+func unlock(fp *os.File) error {
+	return syscall.Flock(int(fp.Fd()), syscall.LOCK_UN)
+}
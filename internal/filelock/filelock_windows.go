@@ -0,0 +1,15 @@
+package filelock
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+func lock(fp *os.File) error {
+	return windows.LockFileEx(windows.Handle(fp.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK, 0, 1, 0, &windows.Overlapped{})
+}
+
+func unlock(fp *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(fp.Fd()), 0, 1, 0, &windows.Overlapped{})
+}
@@ -0,0 +1,84 @@
+package guard
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/rafaelmartins/b8r/internal/utils"
+)
+
+type Command struct {
+	mtx    sync.Mutex
+	start  string
+	stop   string
+	active bool
+}
+
+func NewCommand(start string, stop string) (*Command, error) {
+	if start == "" && stop == "" {
+		return nil, fmt.Errorf("guard: command: start or stop command required")
+	}
+
+	return &Command{
+		start: start,
+		stop:  stop,
+	}, nil
+}
+
+func (c *Command) run(cmd string) error {
+	if cmd == "" {
+		return nil
+	}
+
+	e := utils.Shell(cmd)
+	e.Stdout = os.Stderr
+	e.Stderr = os.Stderr
+	if err := e.Run(); err != nil {
+		return fmt.Errorf("guard: command: %q: %w", cmd, err)
+	}
+	return nil
+}
+
+// both mute and pause policies start the command, it must run only once.
+func (c *Command) activate() error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.active {
+		return nil
+	}
+	c.active = true
+	return c.run(c.start)
+}
+
+func (c *Command) deactivate() error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if !c.active {
+		return nil
+	}
+	c.active = false
+	return c.run(c.stop)
+}
+
+func (c *Command) Mute() error {
+	return c.activate()
+}
+
+func (c *Command) Unmute() error {
+	return c.deactivate()
+}
+
+func (c *Command) Pause() error {
+	return c.activate()
+}
+
+func (c *Command) Play() error {
+	return c.deactivate()
+}
+
+func (c *Command) Close() error {
+	return c.deactivate()
+}
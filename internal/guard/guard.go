@@ -0,0 +1,16 @@
+package guard
+
+import (
+	"errors"
+)
+
+var ErrTypeNotSupported = errors.New("guard: type not supported")
+
+// Guard is something that should be quiet while mpv is playing.
+type Guard interface {
+	Mute() error
+	Unmute() error
+	Pause() error
+	Play() error
+	Close() error
+}
@@ -0,0 +1,147 @@
+package guard
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/rafaelmartins/b8r/internal/mpris"
+)
+
+const (
+	mprisPrefix = "org.mpris.MediaPlayer2."
+	mprisPath   = "/org/mpris/MediaPlayer2"
+	mprisPlayer = "org.mpris.MediaPlayer2.Player"
+)
+
+// Mpris pauses and mutes media players through their MPRIS D-Bus interface.
+type Mpris struct {
+	mtx     sync.Mutex
+	conn    *dbus.Conn
+	player  string
+	paused  []string
+	volumes map[string]float64
+}
+
+// NewMpris creates a guard for all the players with bus names starting with
+// org.mpris.MediaPlayer2.<player>, or every player if player is empty.
+func NewMpris(player string) (*Mpris, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("guard: mpris: %w", err)
+	}
+
+	return &Mpris{
+		conn:    conn,
+		player:  player,
+		volumes: map[string]float64{},
+	}, nil
+}
+
+func (m *Mpris) players() ([]string, error) {
+	names := []string{}
+	if err := m.conn.BusObject().Call("org.freedesktop.DBus.ListNames", 0).Store(&names); err != nil {
+		return nil, fmt.Errorf("guard: mpris: %w", err)
+	}
+
+	rv := []string{}
+	for _, name := range names {
+		// never guard against ourselves
+		if strings.HasPrefix(name, mprisPrefix+m.player) && name != mpris.BusName() {
+			rv = append(rv, name)
+		}
+	}
+	return rv, nil
+}
+
+func (m *Mpris) Pause() error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	players, err := m.players()
+	if err != nil {
+		return err
+	}
+
+	for _, p := range players {
+		obj := m.conn.Object(p, mprisPath)
+
+		status, err := obj.GetProperty(mprisPlayer + ".PlaybackStatus")
+		if err != nil {
+			continue
+		}
+		if s, ok := status.Value().(string); !ok || s != "Playing" {
+			continue
+		}
+
+		if err := obj.Call(mprisPlayer+".Pause", 0).Err; err != nil {
+			return fmt.Errorf("guard: mpris: %s: %w", p, err)
+		}
+		m.paused = append(m.paused, p)
+	}
+	return nil
+}
+
+func (m *Mpris) Play() error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	// only resume what we paused, players may have been closed meanwhile.
+	paused := m.paused
+	m.paused = nil
+	for _, p := range paused {
+		m.conn.Object(p, mprisPath).Call(mprisPlayer+".Play", 0)
+	}
+	return nil
+}
+
+func (m *Mpris) Mute() error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	players, err := m.players()
+	if err != nil {
+		return err
+	}
+
+	for _, p := range players {
+		if _, found := m.volumes[p]; found {
+			continue
+		}
+
+		obj := m.conn.Object(p, mprisPath)
+
+		volume, err := obj.GetProperty(mprisPlayer + ".Volume")
+		if err != nil {
+			continue
+		}
+		v, ok := volume.Value().(float64)
+		if !ok || v == 0 {
+			continue
+		}
+
+		if err := obj.SetProperty(mprisPlayer+".Volume", dbus.MakeVariant(float64(0))); err != nil {
+			return fmt.Errorf("guard: mpris: %s: %w", p, err)
+		}
+		m.volumes[p] = v
+	}
+	return nil
+}
+
+func (m *Mpris) Unmute() error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	for p, v := range m.volumes {
+		m.conn.Object(p, mprisPath).SetProperty(mprisPlayer+".Volume", dbus.MakeVariant(v))
+	}
+	m.volumes = map[string]float64{}
+	return nil
+}
+
+func (m *Mpris) Close() error {
+	m.Unmute()
+	m.Play()
+	return m.conn.Close()
+}
@@ -0,0 +1,88 @@
+package guard
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// PulseAudio mutes an audio sink with pactl, that also works with pipewire-pulse.
+type PulseAudio struct {
+	mtx   sync.Mutex
+	sink  string
+	muted bool
+}
+
+func NewPulseAudio(sink string) (*PulseAudio, error) {
+	if _, err := exec.LookPath("pactl"); err != nil {
+		return nil, fmt.Errorf("guard: pulseaudio: %w", err)
+	}
+
+	if sink == "" {
+		sink = "@DEFAULT_SINK@"
+	}
+
+	return &PulseAudio{
+		sink: sink,
+	}, nil
+}
+
+func (p *PulseAudio) pactl(args ...string) (string, error) {
+	out, err := exec.Command("pactl", args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("guard: pulseaudio: pactl %s: %w", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (p *PulseAudio) Mute() error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if p.muted {
+		return nil
+	}
+
+	// sinks muted by someone else must stay muted after we are done
+	out, err := p.pactl("get-sink-mute", p.sink)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(out, "yes") {
+		return nil
+	}
+
+	if _, err := p.pactl("set-sink-mute", p.sink, "1"); err != nil {
+		return err
+	}
+	p.muted = true
+	return nil
+}
+
+func (p *PulseAudio) Unmute() error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if !p.muted {
+		return nil
+	}
+
+	if _, err := p.pactl("set-sink-mute", p.sink, "0"); err != nil {
+		return err
+	}
+	p.muted = false
+	return nil
+}
+
+func (p *PulseAudio) Pause() error {
+	return nil
+}
+
+func (p *PulseAudio) Play() error {
+	return nil
+}
+
+func (p *PulseAudio) Close() error {
+	return p.Unmute()
+}
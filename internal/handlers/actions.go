@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rafaelmartins/b8r/internal/favorites"
+	"github.com/rafaelmartins/b8r/internal/mpv/client"
+	"rafaelmartins.com/p/octokeyz"
+)
+
+type Press int
+
+const (
+	PressShort Press = iota
+	PressLong
+	PressModShort
+	PressModLong
+	PressHold
+)
+
+var (
+	ErrActionNotFound = errors.New("handlers: action not found")
+
+	pressNames = map[string]Press{
+		"short":     PressShort,
+		"long":      PressLong,
+		"mod-short": PressModShort,
+		"mod-long":  PressModLong,
+		"hold":      PressHold,
+	}
+
+	// seek buttons only support hold actions, and the modifier button can't be bound
+	bindableButtons = []octokeyz.ButtonID{
+		octokeyz.BUTTON_1,
+		octokeyz.BUTTON_2,
+		octokeyz.BUTTON_6,
+		octokeyz.BUTTON_7,
+		octokeyz.BUTTON_8,
+	}
+	holdBindableButtons = []octokeyz.ButtonID{
+		octokeyz.BUTTON_1,
+		octokeyz.BUTTON_2,
+		octokeyz.BUTTON_3,
+		octokeyz.BUTTON_4,
+		octokeyz.BUTTON_6,
+		octokeyz.BUTTON_7,
+		octokeyz.BUTTON_8,
+	}
+
+	actions     = map[string]Action{}
+	holdActions = map[string]holdAction{}
+	bindings    = map[octokeyz.ButtonID]map[Press]string{}
+
+	favs *favorites.Store
+
+	flashMtx sync.Mutex
+	flashGen int
+)
+
+type Action func(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session) error
+
+type holdAction func(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session) *holdCallbacks
+
+func init() {
+	actions["favorite"] = func(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session) error {
+		return addFavorite(dev, m, s, false)
+	}
+	actions["favorite-position"] = func(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session) error {
+		return addFavorite(dev, m, s, true)
+	}
+}
+
+// AddAction registers an action implemented outside of this package. it must be
+// called before binding buttons.
+func AddAction(name string, a Action) {
+	actions[name] = a
+}
+
+func ListActions() []string {
+	rv := []string{}
+	for k := range actions {
+		rv = append(rv, k)
+	}
+	for k := range holdActions {
+		rv = append(rv, k)
+	}
+	slices.Sort(rv)
+	return rv
+}
+
+func Bind(button int, press string, action string) error {
+	btn := octokeyz.ButtonID(button)
+
+	p, found := pressNames[press]
+	if !found {
+		return fmt.Errorf("handlers: invalid press: %s", press)
+	}
+
+	if p == PressHold {
+		if !slices.Contains(holdBindableButtons, btn) {
+			return fmt.Errorf("handlers: button can't be bound: %d", button)
+		}
+		if _, found := holdActions[action]; !found {
+			return fmt.Errorf("%w: %s", ErrActionNotFound, action)
+		}
+	} else {
+		if !slices.Contains(bindableButtons, btn) {
+			return fmt.Errorf("handlers: button can't be bound: %d", button)
+		}
+		if _, found := actions[action]; !found {
+			return fmt.Errorf("%w: %s", ErrActionNotFound, action)
+		}
+	}
+
+	if bindings[btn] == nil {
+		bindings[btn] = map[Press]string{}
+	}
+
+	// hold actions take over the button, there's no way to detect other presses
+	_, hold := bindings[btn][PressHold]
+	if (p == PressHold && len(bindings[btn]) > 0 && !hold) || (p != PressHold && hold) {
+		return fmt.Errorf("handlers: button can't be bound to hold and press actions: %d", button)
+	}
+	bindings[btn][p] = action
+	return nil
+}
+
+func octokeyzBoundHoldHandler(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session, btn octokeyz.ButtonID, def octokeyz.ButtonHandler) octokeyz.ButtonHandler {
+	if name, found := bindings[btn][PressHold]; found {
+		return octokeyzHoldHandler(holdActions[name](dev, m, s))
+	}
+	return def
+}
+
+func octokeyzBoundHandler(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session, btn octokeyz.ButtonID, short octokeyz.ButtonHandler, long octokeyz.ButtonHandler, modShort octokeyz.ButtonHandler, modLong octokeyz.ButtonHandler) octokeyz.ButtonHandler {
+	if _, found := bindings[btn][PressHold]; found {
+		return octokeyzBoundHoldHandler(dev, m, s, btn, nil)
+	}
+
+	h := []octokeyz.ButtonHandler{short, long, modShort, modLong}
+	for p, name := range bindings[btn] {
+		a := actions[name]
+		h[p] = func(b *octokeyz.Button) error {
+			return a(dev, m, s)
+		}
+	}
+	return octokeyzHandler(dev, h[PressShort], h[PressLong], h[PressModShort], h[PressModLong])
+}
+
+func DisplayFlash(dev *octokeyz.Device, msg string) error {
+	return displayFlash(dev, msg)
+}
+
+func displayFlash(dev *octokeyz.Device, msg string) error {
+	flashMtx.Lock()
+	defer flashMtx.Unlock()
+
+	flashGen++
+	gen := flashGen
+
+	if err := disp.Override(octokeyz.DisplayLine8, msg, octokeyz.DisplayLineAlignLeft); err != nil {
+		return err
+	}
+
+	time.AfterFunc(3*time.Second, func() {
+		flashMtx.Lock()
+		defer flashMtx.Unlock()
+
+		// a newer message is being displayed
+		if gen == flashGen {
+			disp.ClearOverride(octokeyz.DisplayLine8)
+		}
+	})
+	return nil
+}
+
+func SetFavorites(s *favorites.Store) {
+	favs = s
+}
+
+func addFavorite(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session, withPosition bool) error {
+	if favs == nil {
+		return errors.New("handlers: favorites not available")
+	}
+
+	item := s.getItem()
+	fav := &favorites.Favorite{
+		Source: item.sourceName,
+		Table:  s.Table(),
+		Entry:  item.key,
+		File:   item.file,
+		Title:  item.current,
+	}
+
+	// plugin sessions do not know anything about what is playing
+	if fav.Entry == "" {
+		path, err := m.GetPropertyString("path")
+		if err != nil {
+			if errors.Is(err, client.ErrMpvPropertyUnavailable) {
+				return nil
+			}
+			return err
+		}
+		title, err := m.GetPropertyString("media-title")
+		if err != nil {
+			title = path
+		}
+
+		fav.Source = "mpv"
+		fav.Entry = path
+		fav.File = path
+		fav.Title = title
+	}
+
+	if withPosition {
+		if pos, err := m.GetPropertyFloat64("time-pos"); err == nil {
+			fav.Position = pos
+		}
+	}
+
+	f, err := favs.Add(fav)
+	if err != nil {
+		return err
+	}
+	return displayFlash(dev, fmt.Sprintf("Fav %d: %s", f.ID, f.Title))
+}
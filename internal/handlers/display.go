@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"github.com/rafaelmartins/b8r/internal/display"
+	"github.com/rafaelmartins/b8r/internal/mpv/client"
+	"rafaelmartins.com/p/octokeyz"
+)
+
+var disp *display.Layout
+
+func init() {
+	actions["display-page-next"] = func(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session) error {
+		return displayPage(dev, 1)
+	}
+	actions["display-page-prev"] = func(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session) error {
+		return displayPage(dev, -1)
+	}
+}
+
+func SetDisplay(l *display.Layout) {
+	disp = l
+}
+
+func displayPage(dev *octokeyz.Device, step int) error {
+	if err := disp.NextPage(step); err != nil {
+		return err
+	}
+	return displayFlash(dev, "Page: "+disp.PageName())
+}
@@ -5,13 +5,15 @@ import (
 	"fmt"
 	"log"
 	"math"
-	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/rafaelmartins/b8r/internal/androidtv"
+	"github.com/rafaelmartins/b8r/internal/guard"
+	"github.com/rafaelmartins/b8r/internal/hooks"
 	"github.com/rafaelmartins/b8r/internal/mpv/client"
-	"github.com/rafaelmartins/b8r/internal/source"
-	"github.com/rafaelmartins/b8r/internal/utils"
 	"rafaelmartins.com/p/octokeyz"
 )
 
@@ -23,20 +25,22 @@ var (
 	keySeek60Fwd = []any{"osd-bar", "seek", 60}
 	keySeek60Bwd = []any{"osd-bar", "seek", -60}
 
-	waitingPlayback = false
-	current         = ""
-	next            = ""
-	supportsNext    = false
-	idxTotal        = 0
-	idxCurrent      = 0
+	hks *hooks.Hooks
 
-	atv        *androidtv.Remote
-	atvMuting  = false
-	atvPausing = false
+	// android-tv devices may be added after the handlers are registered, when paired in background
+	guardsMtx sync.Mutex
+	guardsDev *octokeyz.Device
+	guardsMpv *client.MpvIpcClient
+	atvs      []*atvDevice
+	guards    []*guardEntry
 )
 
 func octokeyzHandler(dev *octokeyz.Device, short octokeyz.ButtonHandler, long octokeyz.ButtonHandler, modShort octokeyz.ButtonHandler, modLong octokeyz.ButtonHandler) octokeyz.ButtonHandler {
 	return func(b *octokeyz.Button) error {
+		if inputActive() {
+			return nil
+		}
+
 		lpDuration := 400 * time.Millisecond
 		done := make(chan struct{})
 
@@ -93,66 +97,252 @@ func octokeyzHandler(dev *octokeyz.Device, short octokeyz.ButtonHandler, long oc
 	}
 }
 
-func octokeyzHoldKeyHandler(m *client.MpvIpcClient, cmd []any, modCmd []any) octokeyz.ButtonHandler {
+type holdCallbacks struct {
+	press   func() error
+	held    func() error
+	repeat  func() error
+	release func(held bool) error
+}
+
+// press is called when the button is pressed, held once the button is held for
+// the auto-repeat delay, repeat at the auto-repeat rate after that, and release
+// when the button is released.
+func octokeyzHoldHandler(h *holdCallbacks) octokeyz.ButtonHandler {
 	return func(b *octokeyz.Button) error {
+		if inputActive() {
+			return nil
+		}
+
 		arDelay := 200 * time.Millisecond
 		arRate := (1 * time.Second) / 40
 
-		c := cmd
-		if mod.Pressed() {
-			c = modCmd
-		}
-
-		if _, err := m.Command(c...); err != nil && !errors.Is(err, client.ErrMpvCommand) {
-			return err
+		if h.press != nil {
+			if err := h.press(); err != nil {
+				return err
+			}
 		}
-		time.Sleep(arDelay)
-
-		done := make(chan struct{})
 
+		released := make(chan struct{})
 		go func() {
-			ticker := time.NewTicker(arRate)
-			defer ticker.Stop()
+			b.WaitForRelease()
+			close(released)
+		}()
 
-			for {
-				select {
-				case <-done:
-					return
-				case <-ticker.C:
-					if _, err := m.Command(c...); err != nil && !errors.Is(err, client.ErrMpvCommand) {
-						log.Printf("error: %s", err) // FIXME
-						return
+		held := false
+		select {
+		case <-released:
+		case <-time.After(arDelay):
+			held = true
+			if h.held != nil {
+				if err := h.held(); err != nil {
+					<-released
+					return err
+				}
+			}
+
+			if h.repeat != nil {
+				ticker := time.NewTicker(arRate)
+			loop:
+				for {
+					select {
+					case <-released:
+						break loop
+					case <-ticker.C:
+						if err := h.repeat(); err != nil {
+							ticker.Stop()
+							<-released
+							return err
+						}
 					}
 				}
+				ticker.Stop()
 			}
-		}()
+			<-released
+		}
 
-		b.WaitForRelease()
-		close(done)
+		if h.release != nil {
+			return h.release(held)
+		}
+		return nil
+	}
+}
 
+func octokeyzHoldKeyHandler(m *client.MpvIpcClient, cmd []any, modCmd []any) octokeyz.ButtonHandler {
+	c := cmd
+	run := func() error {
+		if _, err := m.Command(c...); err != nil && !errors.Is(err, client.ErrMpvCommand) {
+			return err
+		}
 		return nil
 	}
+
+	return octokeyzHoldHandler(&holdCallbacks{
+		press: func() error {
+			c = cmd
+			if mod.Pressed() {
+				c = modCmd
+			}
+			return run()
+		},
+		repeat: run,
+	})
+}
+
+type atvDevice struct {
+	name   string
+	remote *androidtv.Remote
+}
+
+type guardEntry struct {
+	name    string
+	guard   guard.Guard
+	muting  bool
+	pausing bool
+}
+
+func SetHooks(h *hooks.Hooks) {
+	hks = h
+}
+
+func GuardAdd(name string, g guard.Guard, muting bool, pausing bool) {
+	guardsMtx.Lock()
+	defer guardsMtx.Unlock()
+
+	guards = append(guards, &guardEntry{
+		name:    name,
+		guard:   g,
+		muting:  muting,
+		pausing: pausing,
+	})
+}
+
+func AndroidTvAdd(name string, a *androidtv.Remote, muting bool, pausing bool) {
+	g := &guardEntry{
+		name:    name,
+		guard:   a,
+		muting:  muting,
+		pausing: pausing,
+	}
+
+	guardsMtx.Lock()
+	atvs = append(atvs, &atvDevice{
+		name:   name,
+		remote: a,
+	})
+	guards = append(guards, g)
+	dev, m := guardsDev, guardsMpv
+	guardsMtx.Unlock()
+
+	// handlers already registered, catch up with them
+	if dev == nil {
+		return
+	}
+	atvWatch(dev, a)
+	if err := guardsUpdateDisplay(dev); err != nil {
+		log.Printf("error: %s", err)
+	}
+	if mpvIsPlaying(m) {
+		if err := guardsStartList([]guardEntry{*g}); err != nil {
+			log.Printf("error: %s", err)
+		}
+	}
 }
 
-func AndroidTvInit(a *androidtv.Remote, muting bool, pausing bool) {
-	atv = a
-	atvMuting = muting
-	atvPausing = pausing
+func atvWatch(dev *octokeyz.Device, a *androidtv.Remote) {
+	a.AddStateHandler(func(r *androidtv.Remote, state androidtv.State) {
+		if err := guardsUpdateDisplay(dev); err != nil {
+			log.Printf("error: %s", err)
+		}
+	})
 }
 
-func atvUpdateDisplay(dev *octokeyz.Device) error {
-	if atv == nil {
+// guardsList returns copies, the policies may be toggled while guards are being called.
+func guardsList() ([]*atvDevice, []guardEntry) {
+	guardsMtx.Lock()
+	defer guardsMtx.Unlock()
+
+	rv := []guardEntry{}
+	for _, g := range guards {
+		rv = append(rv, *g)
+	}
+	return slices.Clone(atvs), rv
+}
+
+func guardsUpdateDisplay(dev *octokeyz.Device) error {
+	atvs, guards := guardsList()
+	if len(guards) == 0 {
 		return nil
 	}
 
-	c := []byte{' ', ' ', 0}
-	if atvMuting {
-		c[0] = 'M'
+	c := []byte{' ', ' '}
+	for _, g := range guards {
+		if g.muting {
+			c[0] = 'M'
+		}
+		if g.pausing {
+			c[1] = 'P'
+		}
+	}
+
+	if len(atvs) == 0 {
+		return disp.Set("guards", fmt.Sprintf("G:%d %s", len(guards), c))
+	}
+
+	connected := 0
+	for _, a := range atvs {
+		if a.remote.State().Connection == androidtv.ConnectionConnected {
+			connected++
+		}
 	}
-	if atvPausing {
-		c[1] = 'P'
+
+	state := atvs[0].remote.State()
+
+	conn := state.Connection.String()
+	if len(atvs) > 1 {
+		conn = fmt.Sprintf("%d/%d", connected, len(atvs))
 	}
-	return utils.IgnoreDisplayMissing(dev.DisplayLine(octokeyz.DisplayLine2, string(c), octokeyz.DisplayLineAlignRight))
+	if err := disp.Set("guards", fmt.Sprintf("TV:%s %s", conn, c)); err != nil {
+		return err
+	}
+
+	for i, a := range atvs {
+		app, vol := atvDescribe(a.remote.State())
+		if i == 0 {
+			name := ""
+			if len(atvs) > 1 {
+				name = a.name + ": "
+			}
+			if err := disp.Set("atv-app", name+app); err != nil {
+				return err
+			}
+			if err := disp.Set("atv-volume", vol); err != nil {
+				return err
+			}
+		}
+		if err := disp.Set(fmt.Sprintf("atv-%d", i+1), strings.TrimSpace(fmt.Sprintf("%s: %s %s", a.name, app, vol))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func atvDescribe(state androidtv.State) (string, string) {
+	vol := "V:--"
+	if state.Connection != androidtv.ConnectionConnected {
+		vol = ""
+	} else if state.VolumeMuted {
+		vol = "V:M"
+	} else if state.VolumeMax > 0 {
+		vol = fmt.Sprintf("V:%d", state.VolumeLevel)
+	}
+
+	app := strings.TrimPrefix(state.AppPackage, "com.")
+	if state.Connection == androidtv.ConnectionConnected && !state.PowerOn {
+		app = "off"
+	} else if app == "" || state.Connection != androidtv.ConnectionConnected {
+		app = "-"
+	}
+	return app, vol
 }
 
 func mpvIsPlaying(m *client.MpvIpcClient) bool {
@@ -165,284 +355,283 @@ func mpvIsPlaying(m *client.MpvIpcClient) bool {
 	return true
 }
 
-func atvToggleMuting(dev *octokeyz.Device, m *client.MpvIpcClient) error {
-	if atv == nil {
+func guardError(g guardEntry, err error) error {
+	if err == nil {
 		return nil
 	}
+	return fmt.Errorf("%s: %w", g.name, err)
+}
 
-	atvMuting = !atvMuting
-
-	if mpvIsPlaying(m) {
-		var err error
-		if atvMuting {
-			err = atv.Mute()
-		} else {
-			err = atv.Unmute()
-		}
-		if err != nil {
-			return err
-		}
+func onOff(v bool) string {
+	if v {
+		return "on"
 	}
-
-	return atvUpdateDisplay(dev)
+	return "off"
 }
 
-func atvTogglePausing(dev *octokeyz.Device, m *client.MpvIpcClient) error {
-	if atv == nil {
+// guards may disagree after being configured individually, toggling sets all
+// of them to the same value: off if any of them is enabled, on otherwise.
+func guardsToggleMuting(dev *octokeyz.Device, m *client.MpvIpcClient) error {
+	guardsMtx.Lock()
+	if len(guards) == 0 {
+		guardsMtx.Unlock()
 		return nil
 	}
 
-	atvPausing = !atvPausing
+	muting := true
+	for _, g := range guards {
+		if g.muting {
+			muting = false
+			break
+		}
+	}
+
+	changed := []guardEntry{}
+	for _, g := range guards {
+		if g.muting != muting {
+			g.muting = muting
+			changed = append(changed, *g)
+		}
+	}
+	guardsMtx.Unlock()
 
 	if mpvIsPlaying(m) {
-		var err error
-		if atvPausing {
-			err = atv.Pause()
-		} else {
-			err = atv.Play()
+		errs := []error{}
+		for _, g := range changed {
+			if muting {
+				errs = append(errs, guardError(g, g.guard.Mute()))
+			} else {
+				errs = append(errs, guardError(g, g.guard.Unmute()))
+			}
 		}
-		if err != nil {
+		if err := errors.Join(errs...); err != nil {
 			return err
 		}
 	}
 
-	return atvUpdateDisplay(dev)
+	if err := guardsUpdateDisplay(dev); err != nil {
+		return err
+	}
+	return displayFlash(dev, "Guards muting: "+onOff(muting))
 }
 
-func atvMute() error {
-	if atv == nil {
+func guardsTogglePausing(dev *octokeyz.Device, m *client.MpvIpcClient) error {
+	guardsMtx.Lock()
+	if len(guards) == 0 {
+		guardsMtx.Unlock()
 		return nil
 	}
-	if atvPausing {
-		if err := atv.Pause(); err != nil {
-			return err
+
+	pausing := true
+	for _, g := range guards {
+		if g.pausing {
+			pausing = false
+			break
 		}
 	}
-	if atvMuting {
-		if err := atv.Mute(); err != nil {
-			return err
+
+	changed := []guardEntry{}
+	for _, g := range guards {
+		if g.pausing != pausing {
+			g.pausing = pausing
+			changed = append(changed, *g)
 		}
 	}
-	return nil
-}
+	guardsMtx.Unlock()
 
-func atvUnmute() error {
-	if atv == nil {
-		return nil
-	}
-	if atvMuting {
-		if err := atv.Unmute(); err != nil {
-			return err
+	if mpvIsPlaying(m) {
+		errs := []error{}
+		for _, g := range changed {
+			if pausing {
+				errs = append(errs, guardError(g, g.guard.Pause()))
+			} else {
+				errs = append(errs, guardError(g, g.guard.Play()))
+			}
 		}
-	}
-	if atvPausing {
-		if err := atv.Play(); err != nil {
+		if err := errors.Join(errs...); err != nil {
 			return err
 		}
 	}
-	return nil
-}
-
-func LoadNextFile(m *client.MpvIpcClient, src *source.Source) error {
-	if m == nil {
-		return errors.New("handlers: missing mpv")
-	}
-	if src == nil {
-		return errors.New("handlers: missing source")
-	}
 
-	var err error
-	current, err = src.NextItem()
-	if err != nil {
+	if err := guardsUpdateDisplay(dev); err != nil {
 		return err
 	}
+	return displayFlash(dev, "Guards pausing: "+onOff(pausing))
+}
 
-	idxTotal = src.GetItemsCount()
-	idxCurrent = idxTotal - src.GetCurrentItemsCount()
+func guardsStart() error {
+	_, guards := guardsList()
+	return guardsStartList(guards)
+}
 
-	next, supportsNext, err = src.LookAheadItem()
-	if err != nil {
-		return err
+func guardsStartList(guards []guardEntry) error {
+	errs := []error{}
+	for _, g := range guards {
+		if g.pausing {
+			if err := g.guard.Pause(); err != nil {
+				errs = append(errs, guardError(g, err))
+				continue
+			}
+		}
+		if g.muting {
+			errs = append(errs, guardError(g, g.guard.Mute()))
+		}
 	}
+	return errors.Join(errs...)
+}
 
-	if supportsNext {
-		next, err = src.FormatItem(next)
-		if err != nil {
-			return err
+func guardsStop() error {
+	_, guards := guardsList()
+
+	errs := []error{}
+	for _, g := range guards {
+		if g.muting {
+			if err := g.guard.Unmute(); err != nil {
+				errs = append(errs, guardError(g, err))
+				continue
+			}
+		}
+		if g.pausing {
+			errs = append(errs, guardError(g, g.guard.Play()))
 		}
 	}
+	return errors.Join(errs...)
+}
 
-	file, err := src.GetFile(current)
-	if err != nil {
+func Play(m *client.MpvIpcClient) error {
+	if err := guardsStart(); err != nil {
 		return err
 	}
-
-	current, err = src.FormatItem(current)
-	if err != nil {
+	if err := m.SetProperty("pause", false); err != nil {
 		return err
 	}
+	return m.SetProperty("fullscreen", true)
+}
 
-	if err := m.SetProperty("osd-playing-msg", filepath.ToSlash(current)); err != nil {
+func Pause(m *client.MpvIpcClient) error {
+	if err := guardsStop(); err != nil {
 		return err
 	}
 	if err := m.SetProperty("pause", true); err != nil {
 		return err
 	}
-	if err := m.SetProperty("fullscreen", true); err != nil {
-		return err
-	}
-	if _, err := m.Command("vf", "remove", "hflip"); err != nil {
-		return err
-	}
-	if _, err := m.Command("vf", "remove", "vflip"); err != nil {
-		return err
-	}
-	if err := m.SetProperty("video-align-x", 0); err != nil {
-		return err
-	}
-	if err := m.SetProperty("video-align-y", 0); err != nil {
+	return m.SetProperty("fullscreen", false)
+}
+
+func PlayPause(m *client.MpvIpcClient) error {
+	paused, err := m.GetPropertyBool("pause")
+	if err != nil {
 		return err
 	}
-	if err := m.SetProperty("video-rotate", 0); err != nil {
-		return err
+	if paused {
+		return Play(m)
 	}
-	if err := m.SetProperty("video-zoom", 0); err != nil {
+	return Pause(m)
+}
+
+func Stop(m *client.MpvIpcClient) error {
+	if err := guardsStop(); err != nil {
 		return err
 	}
-
-	waitingPlayback = true
-
-	_, err = m.Command("loadfile", file)
+	_, err := m.Command("stop")
 	return err
 }
 
-func RegisterOctokeyzHandlers(dev *octokeyz.Device, m *client.MpvIpcClient, src *source.Source, plugin bool) error {
+func RegisterOctokeyzHandlers(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session, plugin bool) error {
 	if dev == nil {
 		return errors.New("handlers: missing device")
 	}
 	if m == nil {
 		return errors.New("handlers: missing mpv")
 	}
+	if s == nil {
+		return errors.New("handlers: missing session")
+	}
 
-	if err := atvUpdateDisplay(dev); err != nil {
+	if err := guardsUpdateDisplay(dev); err != nil {
 		return err
 	}
+	guardsMtx.Lock()
+	guardsDev = dev
+	guardsMpv = m
+	watch := slices.Clone(atvs)
+	guardsMtx.Unlock()
+	for _, a := range watch {
+		atvWatch(dev, a.remote)
+	}
 
-	if src != nil {
-		idxTotal = src.GetItemsCount()
-		idxCurrent = idxTotal - src.GetCurrentItemsCount()
-
-		if err := utils.IgnoreDisplayMissing(dev.DisplayLine(octokeyz.DisplayLine3, fmt.Sprintf("Source: %s", src.GetBackendName()), octokeyz.DisplayLineAlignLeft)); err != nil {
+	if s.HasNext() {
+		if err := s.refreshDisplay(); err != nil {
 			return err
 		}
-		if err := utils.IgnoreDisplayMissing(dev.DisplayLine(octokeyz.DisplayLine4, fmt.Sprintf("%d / %d", idxCurrent, idxTotal), octokeyz.DisplayLineAlignLeft)); err != nil {
-			return err
-		}
-
-		var err error
-		next, supportsNext, err = src.LookAheadItem()
-		if err != nil {
+	} else if plugin {
+		// as this is used by plugin, we won't get the restart-playback event the first time
+		if err := guardsStart(); err != nil {
 			return err
 		}
+	}
 
-		if supportsNext {
-			next, err = src.FormatItem(next)
-			if err != nil {
-				return err
-			}
-			if err := utils.IgnoreDisplayMissing(dev.DisplayLine(octokeyz.DisplayLine7, fmt.Sprintf("N: %s", next), octokeyz.DisplayLineAlignLeft)); err != nil {
-				return err
+	// sessions without a source may switch to one at runtime
+	playNext := func(b *octokeyz.Button) error {
+		if s.HasNext() {
+			if paused, err := m.GetPropertyBool("pause"); err == nil && paused {
+				return Play(m)
 			}
+			return s.Next(m)
 		}
 
-		dev.AddHandler(octokeyz.BUTTON_1, octokeyzHandler(dev,
-			func(b *octokeyz.Button) error {
-				if paused, err := m.GetPropertyBool("pause"); err == nil && paused {
-					if err := atvMute(); err != nil {
-						return err
-					}
-					if err := m.SetProperty("pause", false); err != nil {
-						return err
-					}
-					return m.SetProperty("fullscreen", true)
-				}
-				return LoadNextFile(m, src)
-			},
-			func(b *octokeyz.Button) error {
-				if err := atvUnmute(); err != nil {
+		if paused, err := m.GetPropertyBool("pause"); err == nil {
+			if paused {
+				if err := guardsStart(); err != nil {
 					return err
 				}
-				if err := m.SetProperty("pause", true); err != nil {
+				if err := m.SetProperty("fullscreen", true); err != nil {
 					return err
 				}
-				return m.SetProperty("fullscreen", false)
-			},
-			func(b *octokeyz.Button) error {
-				if cnt, err := m.GetPropertyInt("playlist-count"); err == nil && int(cnt) == 0 {
-					_, err := m.Command("quit")
-					return err
-				}
-
-				if err := atvUnmute(); err != nil {
-					return err
-				}
-				_, err := m.Command("stop")
-				return err
-			},
-			func(b *octokeyz.Button) error {
-				return atvTogglePausing(dev, m)
-			},
-		))
-	} else {
-		if plugin {
-			// as this is used by plugin, we won't get the restart-playback event the first time
-			if err := atvMute(); err != nil {
-				return err
+				return m.SetProperty("pause", false)
 			}
+		} else {
+			return err
 		}
 
-		dev.AddHandler(octokeyz.BUTTON_1, octokeyzHandler(dev,
-			func(b *octokeyz.Button) error {
-				if paused, err := m.GetPropertyBool("pause"); err == nil {
-					if paused {
-						if err := atvMute(); err != nil {
-							return err
-						}
-						if err := m.SetProperty("fullscreen", true); err != nil {
-							return err
-						}
-						return m.SetProperty("pause", false)
-					}
-				} else {
+		if fs, err := m.GetPropertyBool("fullscreen"); err == nil {
+			if fs {
+				if err := guardsStop(); err != nil {
 					return err
 				}
-
-				if fs, err := m.GetPropertyBool("fullscreen"); err == nil {
-					if fs {
-						if err := atvUnmute(); err != nil {
-							return err
-						}
-						if err := m.SetProperty("pause", true); err != nil {
-							return err
-						}
-					}
-					return m.SetProperty("fullscreen", !fs)
-				} else {
+				if err := m.SetProperty("pause", true); err != nil {
 					return err
 				}
-			},
-			nil,
-			func(b *octokeyz.Button) error {
-				_, err := m.Command("quit")
-				return err
-			},
-			func(b *octokeyz.Button) error {
-				return atvTogglePausing(dev, m)
-			},
-		))
+			}
+			return m.SetProperty("fullscreen", !fs)
+		} else {
+			return err
+		}
 	}
 
-	dev.AddHandler(octokeyz.BUTTON_2, octokeyzHandler(dev,
+	dev.AddHandler(octokeyz.BUTTON_1, octokeyzBoundHandler(dev, m, s, octokeyz.BUTTON_1,
+		playNext,
+		func(b *octokeyz.Button) error {
+			if s.HasNext() {
+				return Pause(m)
+			}
+			return playNext(b)
+		},
+		func(b *octokeyz.Button) error {
+			if s.HasNext() {
+				if cnt, err := m.GetPropertyInt("playlist-count"); err != nil || int(cnt) > 0 {
+					return Stop(m)
+				}
+			}
+			_, err := m.Command("quit")
+			return err
+		},
+		func(b *octokeyz.Button) error {
+			return guardsTogglePausing(dev, m)
+		},
+	))
+
+	dev.AddHandler(octokeyz.BUTTON_2, octokeyzBoundHandler(dev, m, s, octokeyz.BUTTON_2,
 		func(b *octokeyz.Button) error {
 			return m.CycleProperty("mute")
 		},
@@ -467,20 +656,20 @@ func RegisterOctokeyzHandlers(dev *octokeyz.Device, m *client.MpvIpcClient, src
 			return err
 		},
 		func(b *octokeyz.Button) error {
-			return atvToggleMuting(dev, m)
+			return guardsToggleMuting(dev, m)
 		},
 	))
 
-	dev.AddHandler(octokeyz.BUTTON_3, octokeyzHoldKeyHandler(m, keySeek5Bwd, keySeek60Bwd))
+	dev.AddHandler(octokeyz.BUTTON_3, octokeyzBoundHoldHandler(dev, m, s, octokeyz.BUTTON_3, octokeyzHoldKeyHandler(m, keySeek5Bwd, keySeek60Bwd)))
 
-	dev.AddHandler(octokeyz.BUTTON_4, octokeyzHoldKeyHandler(m, keySeek5Fwd, keySeek60Fwd))
+	dev.AddHandler(octokeyz.BUTTON_4, octokeyzBoundHoldHandler(dev, m, s, octokeyz.BUTTON_4, octokeyzHoldKeyHandler(m, keySeek5Fwd, keySeek60Fwd)))
 
 	dev.AddHandler(octokeyz.BUTTON_5, mod.Handler)
 	dev.AddHandler(octokeyz.BUTTON_5, func(b *octokeyz.Button) error {
 		return dev.Led(octokeyz.LedFlash)
 	})
 
-	dev.AddHandler(octokeyz.BUTTON_6, octokeyzHandler(dev,
+	dev.AddHandler(octokeyz.BUTTON_6, octokeyzBoundHandler(dev, m, s, octokeyz.BUTTON_6,
 		func(b *octokeyz.Button) error {
 			data, err := m.GetPropertyFloat64("video-zoom")
 			if err != nil {
@@ -510,10 +699,12 @@ func RegisterOctokeyzHandlers(dev *octokeyz.Device, m *client.MpvIpcClient, src
 			}
 			return m.SetProperty("video-zoom", math.Log2(math.Pow(2, data)/1.25))
 		},
-		nil,
+		func(b *octokeyz.Button) error {
+			return screenshot(dev, m, s, false)
+		},
 	))
 
-	dev.AddHandler(octokeyz.BUTTON_7, octokeyzHandler(dev,
+	dev.AddHandler(octokeyz.BUTTON_7, octokeyzBoundHandler(dev, m, s, octokeyz.BUTTON_7,
 		func(b *octokeyz.Button) error {
 			return m.AddProperty("video-align-y", -0.1)
 		},
@@ -528,7 +719,7 @@ func RegisterOctokeyzHandlers(dev *octokeyz.Device, m *client.MpvIpcClient, src
 		},
 	))
 
-	dev.AddHandler(octokeyz.BUTTON_8, octokeyzHandler(dev,
+	dev.AddHandler(octokeyz.BUTTON_8, octokeyzBoundHandler(dev, m, s, octokeyz.BUTTON_8,
 		func(b *octokeyz.Button) error {
 			return m.AddProperty("video-align-x", 0.1)
 		},
@@ -543,57 +734,74 @@ func RegisterOctokeyzHandlers(dev *octokeyz.Device, m *client.MpvIpcClient, src
 		},
 	))
 
-	return nil
+	return observeStatus(dev, m)
 }
 
-func RegisterMPVHandlers(dev *octokeyz.Device, m *client.MpvIpcClient, mute bool, withNext bool) error {
+func RegisterMPVHandlers(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session) error {
 	if dev == nil {
 		return errors.New("handlers: missing device")
 	}
 	if m == nil {
 		return errors.New("handlers: missing mpv ipc client")
 	}
+	if s == nil {
+		return errors.New("handlers: missing session")
+	}
 
 	m.AddHandler("playback-restart", func(mp *client.MpvIpcClient, event string, data map[string]any) error {
-		if !waitingPlayback {
+		if !s.takeWaiting() {
 			return nil
 		}
-		waitingPlayback = false
 
-		if err := atvMute(); err != nil {
+		if !s.isTransformStored() {
+			if err := orientApply(mp, s); err != nil {
+				return err
+			}
+		}
+
+		if err := guardsStart(); err != nil {
 			return err
 		}
 
-		if err := mp.SetProperty("mute", mute); err != nil {
+		if err := mp.SetProperty("mute", s.isMuted()); err != nil {
 			return err
 		}
 		if err := mp.SetProperty("pause", false); err != nil {
 			return err
 		}
 
-		fmt.Printf("Playing: %s\n", current)
-		if withNext {
-			if err := utils.IgnoreDisplayMissing(dev.DisplayLine(octokeyz.DisplayLine4, fmt.Sprintf("%d / %d", idxCurrent, idxTotal), octokeyz.DisplayLineAlignLeft)); err != nil {
+		item := s.getItem()
+
+		fmt.Printf("Playing: %s\n", item.current)
+		playingStart(mp, s, item)
+		if s.HasNext() {
+			if err := disp.Set("index", fmt.Sprintf("%d / %d", item.idxCurrent, item.idxTotal)); err != nil {
 				return err
 			}
 		}
-		if err := utils.IgnoreDisplayMissing(dev.DisplayLine(octokeyz.DisplayLine6, fmt.Sprintf("C: %s", current), octokeyz.DisplayLineAlignLeft)); err != nil {
+		if err := disp.Set("current", item.current); err != nil {
 			return err
 		}
-		if withNext && supportsNext {
-			if err := utils.IgnoreDisplayMissing(dev.DisplayLine(octokeyz.DisplayLine7, fmt.Sprintf("N: %s", next), octokeyz.DisplayLineAlignLeft)); err != nil {
+		if s.HasNext() {
+			if err := disp.Set("next", item.next); err != nil {
 				return err
 			}
 		}
-		return mp.SetProperty("force-media-title", current)
+		if err := mp.SetProperty("force-media-title", item.current); err != nil {
+			return err
+		}
+		return s.Preload(mp)
 	})
 
 	m.AddHandler("end-file", func(mp *client.MpvIpcClient, event string, data map[string]any) error {
-		if data["reason"].(string) == "stop" {
-			return utils.IgnoreDisplayMissing(dev.DisplayClearLine(octokeyz.DisplayLine6))
+		reason, _ := data["reason"].(string)
+		playingEnd(reason)
+
+		if reason == "stop" {
+			return disp.Set("current", "")
 		}
 		return nil
 	})
 
-	return nil
+	return observePlaying(m)
 }
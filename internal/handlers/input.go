@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"rafaelmartins.com/p/octokeyz"
+)
+
+var (
+	ErrInputCanceled = errors.New("handlers: input canceled")
+
+	inputMtx sync.Mutex
+	input    octokeyz.ButtonHandler
+)
+
+const hexDigits = "0123456789ABCDEF"
+
+func inputActive() bool {
+	inputMtx.Lock()
+	defer inputMtx.Unlock()
+	return input != nil
+}
+
+func inputHandler(b *octokeyz.Button) error {
+	inputMtx.Lock()
+	fn := input
+	inputMtx.Unlock()
+
+	if fn == nil {
+		return nil
+	}
+	return fn(b)
+}
+
+func RegisterInputHandlers(dev *octokeyz.Device) error {
+	if dev == nil {
+		return errors.New("handlers: missing device")
+	}
+
+	for btn := octokeyz.BUTTON_1; btn <= octokeyz.BUTTON_8; btn++ {
+		if err := dev.AddHandler(btn, inputHandler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func PairingCodeInput(dev *octokeyz.Device, name string, done <-chan struct{}) (string, error) {
+	if dev == nil {
+		return "", errors.New("handlers: missing device")
+	}
+
+	mtx := sync.Mutex{}
+	code := make([]byte, 6)
+	pos := 0
+	result := make(chan bool, 1)
+
+	format := func() string {
+		rv := ""
+		for i, c := range code {
+			if i == pos {
+				rv += "[" + string(hexDigits[c]) + "]"
+				continue
+			}
+			rv += " " + string(hexDigits[c]) + " "
+		}
+		return strings.TrimSpace(rv)
+	}
+
+	draw := func() error {
+		mtx.Lock()
+		line := format()
+		mtx.Unlock()
+		return disp.Override(octokeyz.DisplayLine4, line, octokeyz.DisplayLineAlignCenter)
+	}
+
+	inputMtx.Lock()
+	if input != nil {
+		inputMtx.Unlock()
+		return "", errors.New("handlers: input already in progress")
+	}
+	input = func(b *octokeyz.Button) error {
+		mtx.Lock()
+		switch b.GetID() {
+		case octokeyz.BUTTON_1:
+			pos = (pos + len(code) - 1) % len(code)
+		case octokeyz.BUTTON_2:
+			pos = (pos + 1) % len(code)
+		case octokeyz.BUTTON_3:
+			code[pos] = (code[pos] + byte(len(hexDigits)) - 1) % byte(len(hexDigits))
+		case octokeyz.BUTTON_4:
+			code[pos] = (code[pos] + 1) % byte(len(hexDigits))
+		case octokeyz.BUTTON_7:
+			select {
+			case result <- false:
+			default:
+			}
+		case octokeyz.BUTTON_8:
+			select {
+			case result <- true:
+			default:
+			}
+		}
+		mtx.Unlock()
+		return draw()
+	}
+	inputMtx.Unlock()
+
+	defer func() {
+		inputMtx.Lock()
+		input = nil
+		inputMtx.Unlock()
+
+		for _, line := range []octokeyz.DisplayLine{octokeyz.DisplayLine2, octokeyz.DisplayLine4, octokeyz.DisplayLine6, octokeyz.DisplayLine7} {
+			disp.ClearOverride(line)
+		}
+	}()
+
+	if err := disp.Override(octokeyz.DisplayLine2, fmt.Sprintf("Pair: %s", name), octokeyz.DisplayLineAlignLeft); err != nil {
+		return "", err
+	}
+	if err := disp.Override(octokeyz.DisplayLine6, "1/2:move 3/4:change", octokeyz.DisplayLineAlignLeft); err != nil {
+		return "", err
+	}
+	if err := disp.Override(octokeyz.DisplayLine7, "7:cancel 8:confirm", octokeyz.DisplayLineAlignLeft); err != nil {
+		return "", err
+	}
+	if err := draw(); err != nil {
+		return "", err
+	}
+
+	select {
+	case ok := <-result:
+		if !ok {
+			return "", ErrInputCanceled
+		}
+		mtx.Lock()
+		defer mtx.Unlock()
+		rv := ""
+		for _, c := range code {
+			rv += string(hexDigits[c])
+		}
+		return rv, nil
+
+	case <-done:
+		return "", ErrInputCanceled
+	}
+}
@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/rafaelmartins/b8r/internal/mpv/client"
+	"rafaelmartins.com/p/octokeyz"
+)
+
+const (
+	menuOverlayID = 1873
+	menuRows      = 15
+	menuPageRows  = 10
+)
+
+type MenuEntry struct {
+	Label   string
+	Select  func() error
+	Enqueue func() error
+}
+
+type menuSection struct {
+	name string
+	list func() ([]*MenuEntry, error)
+}
+
+type menuState struct {
+	sections []*menuSection
+	section  int
+	cursor   int
+	entries  []*MenuEntry
+	err      error
+}
+
+var (
+	menuSections []*menuSection
+
+	menuMtx sync.Mutex
+	menu    *menuState
+)
+
+func init() {
+	actions["menu"] = func(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session) error {
+		return MenuOpen(m, s)
+	}
+}
+
+// AddMenuSection adds a section to the menu, listed after the queue of the
+// source being played.
+func AddMenuSection(name string, list func() ([]*MenuEntry, error)) {
+	menuSections = append(menuSections, &menuSection{
+		name: name,
+		list: list,
+	})
+}
+
+func menuQueue(m *client.MpvIpcClient, s *Session) *menuSection {
+	return &menuSection{
+		name: "Queue",
+		list: func() ([]*MenuEntry, error) {
+			src := s.Source()
+			if src == nil {
+				return nil, nil
+			}
+
+			items, err := src.RemainingItems()
+			if err != nil {
+				return nil, err
+			}
+
+			queued, err := src.QueuedItems()
+			if err != nil {
+				return nil, err
+			}
+
+			rv := []*MenuEntry{}
+			for _, key := range items {
+				label, err := src.FormatItem(key)
+				if err != nil {
+					return nil, err
+				}
+				if slices.Contains(queued, key) {
+					label = "+ " + label
+				}
+				rv = append(rv, &MenuEntry{
+					Label: label,
+					Select: func() error {
+						return s.Load(m, key)
+					},
+					Enqueue: func() error {
+						return s.Enqueue(m, key)
+					},
+				})
+			}
+			return rv, nil
+		},
+	}
+}
+
+func menuEscape(s string) string {
+	// a zero width no-break space after backslashes prevents them from starting
+	// ass escape sequences
+	s = strings.ReplaceAll(s, "\\", "\\\ufeff")
+	s = strings.ReplaceAll(s, "{", "\\{")
+	s = strings.ReplaceAll(s, "}", "\\}")
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+func (s *menuState) load() {
+	s.entries, s.err = s.sections[s.section].list()
+	s.cursor = 0
+}
+
+// reload lists the entries of the section again, keeping the cursor.
+func (s *menuState) reload() {
+	cursor := s.cursor
+	s.load()
+	s.cursor = max(min(cursor, len(s.entries)-1), 0)
+}
+
+func (s *menuState) render() string {
+	rv := "{\\an7\\fs28\\bord2}"
+	for i, sec := range s.sections {
+		if i > 0 {
+			rv += "   "
+		}
+		if i == s.section {
+			rv += "{\\c&H00FFFF&}[" + menuEscape(sec.name) + "]{\\c}"
+		} else {
+			rv += menuEscape(sec.name)
+		}
+	}
+	rv += "\\N\\N"
+
+	if s.err != nil {
+		return rv + "{\\c&H0000FF&}" + menuEscape(s.err.Error()) + "{\\c}"
+	}
+	if len(s.entries) == 0 {
+		return rv + "(empty)"
+	}
+
+	first := min(max(s.cursor-menuRows/2, 0), max(len(s.entries)-menuRows, 0))
+	for i := first; i < len(s.entries) && i < first+menuRows; i++ {
+		if i == s.cursor {
+			rv += "{\\c&H00FFFF&}> " + menuEscape(s.entries[i].Label) + "{\\c}\\N"
+		} else {
+			rv += "   " + menuEscape(s.entries[i].Label) + "\\N"
+		}
+	}
+	return rv + fmt.Sprintf("\\N%d / %d", s.cursor+1, len(s.entries))
+}
+
+func (s *menuState) status() string {
+	return fmt.Sprintf("Menu: %s", s.sections[s.section].name)
+}
+
+func menuDraw(m *client.MpvIpcClient, status string, text string) error {
+	if err := disp.Override(octokeyz.DisplayLine8, status, octokeyz.DisplayLineAlignLeft); err != nil {
+		return err
+	}
+	_, err := m.Command("osd-overlay", menuOverlayID, "ass-events", text)
+	return err
+}
+
+func menuClose(m *client.MpvIpcClient) error {
+	menuMtx.Lock()
+	menu = nil
+	menuMtx.Unlock()
+
+	inputMtx.Lock()
+	input = nil
+	inputMtx.Unlock()
+
+	if err := disp.ClearOverride(octokeyz.DisplayLine8); err != nil {
+		return err
+	}
+	_, err := m.Command("osd-overlay", menuOverlayID, "none", "")
+	return err
+}
+
+// MenuOpen displays the menu in mpv, taking over the macropad buttons until
+// an entry is selected or the menu is closed.
+func MenuOpen(m *client.MpvIpcClient, s *Session) error {
+	if m == nil {
+		return errors.New("handlers: missing mpv")
+	}
+	if s == nil {
+		return errors.New("handlers: missing session")
+	}
+
+	sections := []*menuSection{}
+	if s.HasNext() {
+		sections = append(sections, menuQueue(m, s))
+	}
+	sections = append(sections, menuSections...)
+	if len(sections) == 0 {
+		return errors.New("handlers: menu: no sections")
+	}
+
+	inputMtx.Lock()
+	if input != nil {
+		inputMtx.Unlock()
+		return errors.New("handlers: input already in progress")
+	}
+	input = menuHandler(m)
+	inputMtx.Unlock()
+
+	st := &menuState{
+		sections: sections,
+	}
+
+	menuMtx.Lock()
+	menu = st
+	st.load()
+	status, text := st.status(), st.render()
+	menuMtx.Unlock()
+
+	return menuDraw(m, status, text)
+}
+
+func menuHandler(m *client.MpvIpcClient) octokeyz.ButtonHandler {
+	return func(b *octokeyz.Button) error {
+		menuMtx.Lock()
+		s := menu
+		if s == nil {
+			menuMtx.Unlock()
+			return nil
+		}
+
+		var sel *MenuEntry
+		closing := false
+
+		switch b.GetID() {
+		case octokeyz.BUTTON_1:
+			if s.cursor < len(s.entries) {
+				sel = s.entries[s.cursor]
+			}
+			closing = sel != nil
+		case octokeyz.BUTTON_2:
+			closing = true
+		case octokeyz.BUTTON_3:
+			step := 1
+			if mod.Pressed() {
+				step = menuPageRows
+			}
+			s.cursor = max(s.cursor-step, 0)
+		case octokeyz.BUTTON_4:
+			step := 1
+			if mod.Pressed() {
+				step = menuPageRows
+			}
+			s.cursor = max(min(s.cursor+step, len(s.entries)-1), 0)
+		case octokeyz.BUTTON_6:
+			if s.cursor < len(s.entries) && s.entries[s.cursor].Enqueue != nil {
+				if err := s.entries[s.cursor].Enqueue(); err != nil {
+					menuMtx.Unlock()
+					return err
+				}
+				s.reload()
+			}
+		case octokeyz.BUTTON_7:
+			s.section = (s.section + len(s.sections) - 1) % len(s.sections)
+			s.load()
+		case octokeyz.BUTTON_8:
+			s.section = (s.section + 1) % len(s.sections)
+			s.load()
+		default:
+			menuMtx.Unlock()
+			return nil
+		}
+		status, text := s.status(), s.render()
+		menuMtx.Unlock()
+
+		if !closing {
+			return menuDraw(m, status, text)
+		}
+
+		// the other handlers of this button check for active input when the
+		// button is pressed, it must stay active until the button is released.
+		b.WaitForRelease()
+		if err := menuClose(m); err != nil {
+			return err
+		}
+		if sel == nil {
+			return nil
+		}
+		return sel.Select()
+	}
+}
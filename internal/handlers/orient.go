@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/rafaelmartins/b8r/internal/exif"
+	"github.com/rafaelmartins/b8r/internal/mpv/client"
+)
+
+var (
+	orientAuto  = false
+	orientFit   = ""
+	orientAlign = 0.0
+
+	// mpv applies flip filters before rotation
+	orientExif = map[int]struct {
+		rotate int
+		hflip  bool
+	}{
+		2: {0, true},
+		3: {180, false},
+		4: {180, true},
+		5: {270, true},
+		6: {90, false},
+		7: {90, true},
+		8: {270, false},
+	}
+)
+
+func SetOrientation(auto bool, fit string, align string) error {
+	switch fit {
+	case "", "none", "fill":
+		orientFit = fit
+	default:
+		return fmt.Errorf("handlers: invalid fit mode: %s", fit)
+	}
+
+	switch align {
+	case "top", "left":
+		orientAlign = -1
+	case "", "center":
+		orientAlign = 0
+	case "bottom", "right":
+		orientAlign = 1
+	default:
+		return fmt.Errorf("handlers: invalid fit alignment: %s", align)
+	}
+
+	orientAuto = auto
+	return nil
+}
+
+func orientApply(m *client.MpvIpcClient, s *Session) error {
+	if !orientAuto && orientFit != "fill" {
+		return nil
+	}
+
+	// files with rotation metadata are rotated by mpv itself
+	decRotate, err := m.GetPropertyInt("video-dec-params/rotate")
+	if err != nil {
+		if errors.Is(err, client.ErrMpvPropertyUnavailable) {
+			return nil
+		}
+		return err
+	}
+
+	if orientAuto && decRotate == 0 {
+		if o, err := exif.Orientation(s.getItem().file); err == nil {
+			if t, found := orientExif[o]; found {
+				if t.hflip {
+					if _, err := m.Command("vf", "add", "hflip"); err != nil {
+						return err
+					}
+				}
+				if err := m.SetProperty("video-rotate", t.rotate); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if orientFit != "fill" {
+		return nil
+	}
+
+	w, err := m.GetPropertyFloat64("video-params/dw")
+	if err != nil {
+		return err
+	}
+	h, err := m.GetPropertyFloat64("video-params/dh")
+	if err != nil {
+		return err
+	}
+	ow, err := m.GetPropertyFloat64("osd-width")
+	if err != nil {
+		return err
+	}
+	oh, err := m.GetPropertyFloat64("osd-height")
+	if err != nil {
+		return err
+	}
+	rotate, err := m.GetPropertyInt("video-rotate")
+	if err != nil {
+		return err
+	}
+	if w == 0 || h == 0 || ow == 0 || oh == 0 {
+		return nil
+	}
+
+	if r := (decRotate + rotate) % 360; r == 90 || r == 270 {
+		w, h = h, w
+	}
+
+	// zoom until the media covers the screen, aligning along the cropped axis
+	media := w / h
+	screen := ow / oh
+	switch {
+	case media < screen:
+		if err := m.SetProperty("video-align-y", orientAlign); err != nil {
+			return err
+		}
+		return m.SetProperty("video-zoom", math.Log2(screen/media))
+
+	case media > screen:
+		if err := m.SetProperty("video-align-x", orientAlign); err != nil {
+			return err
+		}
+		return m.SetProperty("video-zoom", math.Log2(media/screen))
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/rafaelmartins/b8r/internal/hooks"
+	"github.com/rafaelmartins/b8r/internal/mpv/client"
+	"github.com/rafaelmartins/b8r/internal/playlog"
+)
+
+// state of the item being played, reported after the session moves to the next item
+type playingItem struct {
+	payload  hooks.Payload
+	key      string
+	started  time.Time
+	pos      float64
+	duration float64
+	skipped  bool
+}
+
+var (
+	playingMtx sync.Mutex
+	playing    *playingItem
+
+	plog *playlog.Log
+)
+
+// playLog records the item being played when closing, as quitting mpv emits no end-file event.
+type playLog struct {
+	*playlog.Log
+}
+
+func (l *playLog) Close() error {
+	playingMtx.Lock()
+	item := playing
+	playing = nil
+	playingMtx.Unlock()
+
+	// hooks are closed by now, the quit event was already fired
+	playingRecord(item)
+	return l.Log.Close()
+}
+
+// SetPlayLog returns the closer to be registered for cleanup instead of the log itself.
+func SetPlayLog(l *playlog.Log) io.Closer {
+	plog = l
+	return &playLog{l}
+}
+
+func playingStart(m *client.MpvIpcClient, s *Session, item sessionItem) {
+	// images have no duration
+	duration, _ := m.GetPropertyFloat64("duration")
+
+	playingMtx.Lock()
+	playing = &playingItem{
+		payload: hooks.Payload{
+			Source: item.sourceName,
+			Table:  s.Table(),
+			Entry:  item.current,
+			File:   item.file,
+			Index:  item.idxCurrent,
+			Total:  item.idxTotal,
+		},
+		key:      item.key,
+		started:  time.Now(),
+		duration: duration,
+	}
+	p := playing.payload
+	playingMtx.Unlock()
+
+	p.Event = hooks.EventItemLoaded
+	hks.Fire(&p)
+}
+
+func playingSkip() {
+	playingMtx.Lock()
+	if playing == nil {
+		playingMtx.Unlock()
+		return
+	}
+	playing.skipped = true
+	p := playing.payload
+	playingMtx.Unlock()
+
+	p.Event = hooks.EventItemSkipped
+	hks.Fire(&p)
+}
+
+func playingEnd(reason string) {
+	playingMtx.Lock()
+	item := playing
+	playing = nil
+	playingMtx.Unlock()
+
+	if item == nil {
+		return
+	}
+
+	p := item.payload
+	p.Event = hooks.EventEndFile
+	p.Reason = reason
+	hks.Fire(&p)
+
+	playingRecord(item)
+}
+
+func playingRecord(item *playingItem) {
+	if item == nil {
+		return
+	}
+
+	// with --loop the position wraps around, the highest position seen is what was watched.
+	// images have no position at all, the wall clock is the best we have.
+	watched, early := playlog.Watched(item.pos, item.duration, time.Since(item.started))
+
+	if err := plog.Append(&playlog.Record{
+		Time:     item.started,
+		Source:   item.payload.Source,
+		Table:    item.payload.Table,
+		Entry:    item.key,
+		Watched:  watched,
+		Duration: item.duration,
+		Skipped:  item.skipped && early,
+	}); err != nil {
+		log.Printf("error: %s", err)
+	}
+}
+
+func observePlaying(m *client.MpvIpcClient) error {
+	if err := m.ObserveProperty("time-pos", func(m *client.MpvIpcClient, property string, value any) error {
+		if v, ok := value.(float64); ok {
+			playingMtx.Lock()
+			if playing != nil && v > playing.pos {
+				playing.pos = v
+			}
+			playingMtx.Unlock()
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return m.ObserveProperty("duration", func(m *client.MpvIpcClient, property string, value any) error {
+		if v, ok := value.(float64); ok {
+			playingMtx.Lock()
+			if playing != nil {
+				playing.duration = v
+			}
+			playingMtx.Unlock()
+		}
+		return nil
+	})
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rafaelmartins/b8r/internal/mpv/client"
+	"rafaelmartins.com/p/octokeyz"
+)
+
+var (
+	screenshotsDir    = ""
+	screenshotsFormat = "png"
+)
+
+func init() {
+	actions["screenshot"] = func(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session) error {
+		return screenshot(dev, m, s, false)
+	}
+	actions["screenshot-osd"] = func(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session) error {
+		return screenshot(dev, m, s, true)
+	}
+}
+
+func SetScreenshots(dir string, format string) {
+	screenshotsDir = dir
+	if format != "" {
+		screenshotsFormat = strings.TrimPrefix(format, ".")
+	}
+}
+
+func sanitizePath(p string) string {
+	parts := []string{}
+	for _, part := range strings.FieldsFunc(filepath.ToSlash(p), func(r rune) bool {
+		return r == '/'
+	}) {
+		if part == "." || part == ".." {
+			continue
+		}
+		parts = append(parts, strings.Map(func(r rune) rune {
+			if strings.ContainsRune(`<>:"\|?*`, r) || r < ' ' {
+				return '_'
+			}
+			return r
+		}, part))
+	}
+	return filepath.Join(parts...)
+}
+
+func screenshotFilename(m *client.MpvIpcClient, s *Session) (string, error) {
+	item := s.getItem()
+	group := s.Table()
+	if group == "" {
+		group = item.sourceName
+	}
+	if group == "" {
+		group = "mpv"
+	}
+
+	name := item.current
+	if name == "" {
+		title, err := m.GetPropertyString("media-title")
+		if err != nil {
+			return "", err
+		}
+		name = title
+	}
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+
+	// images do not have a position
+	if pos, err := m.GetPropertyFloat64("time-pos"); err == nil {
+		ms := int64(pos * 1000)
+		name += fmt.Sprintf("_%02d-%02d-%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+	}
+
+	base := filepath.Join(screenshotsDir, sanitizePath(group), sanitizePath(name))
+	rv := base + "." + screenshotsFormat
+	for i := 1; ; i++ {
+		if _, err := os.Stat(rv); errors.Is(err, os.ErrNotExist) {
+			return rv, nil
+		}
+		rv = fmt.Sprintf("%s-%d.%s", base, i, screenshotsFormat)
+	}
+}
+
+func screenshot(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session, withOsd bool) error {
+	if screenshotsDir == "" {
+		return errors.New("handlers: screenshots directory not set")
+	}
+
+	fn, err := screenshotFilename(m, s)
+	if err != nil {
+		if errors.Is(err, client.ErrMpvPropertyUnavailable) {
+			return nil
+		}
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fn), 0777); err != nil {
+		return err
+	}
+
+	// mpv picks the image format from the file extension
+	flags := "subtitles"
+	if withOsd {
+		flags = "window"
+	}
+	if _, err := m.Command("screenshot-to-file", fn, flags); err != nil {
+		return err
+	}
+	return displayFlash(dev, filepath.Base(fn))
+}
@@ -0,0 +1,600 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/rafaelmartins/b8r/internal/mpv/client"
+	"github.com/rafaelmartins/b8r/internal/source"
+)
+
+type SourceOptions struct {
+	Name       string
+	Table      string
+	Single     bool
+	Mute       bool
+	Alang      string
+	Slang      string
+	AutoOrient bool
+	Fit        string
+	FitAlign   string
+}
+
+// sessionItem is the state of the item loaded from the source.
+type sessionItem struct {
+	key        string
+	file       string
+	current    string
+	sourceName string
+	next       string
+	idxTotal   int
+	idxCurrent int
+}
+
+// Session owns the source being played, that may be switched at runtime, and
+// the state of the item loaded from it.
+//
+// opMtx serializes the operations that change the source or the item loaded
+// from it, as they are triggered by buttons, control commands and mpv events
+// concurrently. mtx guards the fields, read by handlers at any time.
+type Session struct {
+	opMtx  sync.Mutex
+	mtx    sync.Mutex
+	src    *source.Source
+	name   string
+	table  string
+	single bool
+	mute   bool
+
+	waiting         bool
+	startSet        bool
+	transformStored bool
+
+	item    sessionItem
+	nextKey string
+
+	// item appended to the mpv playlist, to be prefetched while the current
+	// one plays
+	preloaded string
+
+	// prefetch-playlist value before the first preload, nil if not changed
+	prefetch any
+}
+
+// NewSession creates a session for a source, that may be nil (e.g. when
+// running as mpv plugin).
+func NewSession(src *source.Source, opts *SourceOptions) (*Session, error) {
+	rv := &Session{}
+	if err := rv.setSource(src, opts); err != nil {
+		return nil, err
+	}
+	return rv, nil
+}
+
+func (s *Session) setSource(src *source.Source, opts *SourceOptions) error {
+	if opts == nil {
+		opts = &SourceOptions{}
+	}
+
+	if err := SetOrientation(opts.AutoOrient, opts.Fit, opts.FitAlign); err != nil {
+		return err
+	}
+	SetTrackLanguages(opts.Alang, opts.Slang)
+
+	s.mtx.Lock()
+	prev := s.src
+	s.src = src
+	s.preloaded = ""
+	s.name = opts.Name
+	s.table = opts.Table
+	s.single = opts.Single
+	s.mute = opts.Mute
+	s.mtx.Unlock()
+
+	if prev != nil && prev != src {
+		if err := prev.Close(); err != nil {
+			log.Printf("error: %s", err)
+		}
+	}
+	return nil
+}
+
+func (s *Session) Close() error {
+	s.opMtx.Lock()
+	defer s.opMtx.Unlock()
+
+	s.mtx.Lock()
+	src := s.src
+	s.src = nil
+	s.mtx.Unlock()
+
+	if src == nil {
+		return nil
+	}
+	return src.Close()
+}
+
+func (s *Session) Source() *source.Source {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.src
+}
+
+func (s *Session) SourceName() string {
+	if src := s.Source(); src != nil {
+		return src.GetBackendName()
+	}
+	return ""
+}
+
+// Name returns the preset, table or source name the session was started or
+// switched with.
+func (s *Session) Name() string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.name
+}
+
+func (s *Session) Table() string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.table
+}
+
+// Current returns the formatted name of the item being played.
+func (s *Session) Current() string {
+	return s.getItem().current
+}
+
+func (s *Session) getItem() sessionItem {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.item
+}
+
+// takeWaiting reports if an item was loaded and did not start playing yet.
+func (s *Session) takeWaiting() bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	rv := s.waiting
+	s.waiting = false
+	return rv
+}
+
+func (s *Session) setTransformStored(stored bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.transformStored = stored
+}
+
+func (s *Session) isTransformStored() bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.transformStored
+}
+
+func (s *Session) isMuted() bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.mute
+}
+
+// HasNext reports if the session can move to a next item. sources with a single
+// entry only load it once.
+func (s *Session) HasNext() bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.src != nil && !s.single
+}
+
+// Switch replaces the source being played, closing the previous one, and loads
+// its first item.
+func (s *Session) Switch(m *client.MpvIpcClient, src *source.Source, opts *SourceOptions) error {
+	if m == nil {
+		return errors.New("handlers: missing mpv")
+	}
+	if src == nil {
+		return errors.New("handlers: missing source")
+	}
+
+	s.opMtx.Lock()
+	defer s.opMtx.Unlock()
+
+	if err := s.setSource(src, opts); err != nil {
+		return err
+	}
+	if err := s.updateDisplay(); err != nil {
+		return err
+	}
+	return s.next(m)
+}
+
+func (s *Session) refreshDisplay() error {
+	s.opMtx.Lock()
+	defer s.opMtx.Unlock()
+	return s.updateDisplay()
+}
+
+// the functions below must be called with opMtx held.
+
+func (s *Session) updateDisplay() error {
+	src := s.Source()
+	if src == nil || !s.HasNext() {
+		// the source may have been switched to a single entry
+		for _, f := range []string{"source", "table", "index", "next"} {
+			if err := disp.Set(f, ""); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	total := src.GetItemsCount()
+	idx := total - src.GetCurrentItemsCount()
+
+	s.mtx.Lock()
+	s.item.idxTotal = total
+	s.item.idxCurrent = idx
+	s.mtx.Unlock()
+
+	if err := disp.Set("source", src.GetBackendName()); err != nil {
+		return err
+	}
+	if err := disp.Set("table", s.Table()); err != nil {
+		return err
+	}
+	if err := disp.Set("index", fmt.Sprintf("%d / %d", idx, total)); err != nil {
+		return err
+	}
+
+	next, err := s.lookAhead(src)
+	if err != nil {
+		return err
+	}
+	return disp.Set("next", next)
+}
+
+func (s *Session) lookAhead(src *source.Source) (string, error) {
+	key, err := src.LookAheadItem()
+	if err != nil {
+		return "", err
+	}
+	next, err := src.FormatItem(key)
+	if err != nil {
+		return "", err
+	}
+
+	s.mtx.Lock()
+	s.nextKey = key
+	s.item.next = next
+	s.mtx.Unlock()
+	return next, nil
+}
+
+// playlistNext returns the file after the current one in the mpv playlist, if
+// any.
+func playlistNext(m *client.MpvIpcClient) string {
+	pos, err := m.GetPropertyInt("playlist-pos")
+	if err != nil || pos < 0 {
+		return ""
+	}
+	count, err := m.GetPropertyInt("playlist-count")
+	if err != nil || pos+1 >= count {
+		return ""
+	}
+	rv, err := m.GetPropertyString(fmt.Sprintf("playlist/%d/filename", pos+1))
+	if err != nil {
+		return ""
+	}
+	return rv
+}
+
+// preload appends the next item to the mpv playlist, replacing any other item
+// appended before.
+func (s *Session) preload(m *client.MpvIpcClient) error {
+	src := s.Source()
+	if src == nil || !s.HasNext() {
+		return nil
+	}
+
+	// without looping, mpv would move to the appended item by itself at the end
+	// of the current one, out of sync with the source.
+	if v, err := m.GetProperty("loop-file"); err != nil || v == false || v == "no" {
+		return nil
+	}
+
+	s.mtx.Lock()
+	nextKey := s.nextKey
+	preloaded := s.preloaded
+	prefetch := s.prefetch
+	s.mtx.Unlock()
+
+	file, err := src.GetFile(nextKey)
+	if err != nil {
+		return err
+	}
+	if preloaded == nextKey && playlistNext(m) == file {
+		return nil
+	}
+
+	if prefetch == nil {
+		v, err := m.GetProperty("prefetch-playlist")
+		if err != nil {
+			return err
+		}
+		if err := m.SetProperty("prefetch-playlist", true); err != nil {
+			return err
+		}
+
+		s.mtx.Lock()
+		s.prefetch = v
+		s.mtx.Unlock()
+	}
+
+	if _, err := m.Command("playlist-clear"); err != nil {
+		return err
+	}
+	if _, err := m.Command("loadfile", file, "append"); err != nil {
+		return err
+	}
+
+	s.mtx.Lock()
+	s.preloaded = nextKey
+	s.mtx.Unlock()
+	return nil
+}
+
+type mpvRestorer struct {
+	s *Session
+	m *client.MpvIpcClient
+}
+
+func (r *mpvRestorer) Close() error {
+	r.s.mtx.Lock()
+	prefetch := r.s.prefetch
+	r.s.prefetch = nil
+	r.s.mtx.Unlock()
+
+	if prefetch == nil {
+		return nil
+	}
+	return r.m.SetProperty("prefetch-playlist", prefetch)
+}
+
+// MpvRestorer returns a closer that reverts the mpv settings changed for
+// preloading, for mpv instances that outlive the session.
+func (s *Session) MpvRestorer(m *client.MpvIpcClient) io.Closer {
+	return &mpvRestorer{
+		s: s,
+		m: m,
+	}
+}
+
+// Next loads the next item of the source.
+func (s *Session) Next(m *client.MpvIpcClient) error {
+	s.opMtx.Lock()
+	defer s.opMtx.Unlock()
+	return s.next(m)
+}
+
+func (s *Session) next(m *client.MpvIpcClient) error {
+	if m == nil {
+		return errors.New("handlers: missing mpv")
+	}
+	src := s.Source()
+	if src == nil {
+		return errors.New("handlers: missing source")
+	}
+
+	playingSkip()
+
+	key, err := src.NextItem()
+	if err != nil {
+		return err
+	}
+	return s.load(m, src, key)
+}
+
+// Load loads a given item of the source, that is not picked again until the
+// source is refilled.
+func (s *Session) Load(m *client.MpvIpcClient, key string) error {
+	s.opMtx.Lock()
+	defer s.opMtx.Unlock()
+
+	if m == nil {
+		return errors.New("handlers: missing mpv")
+	}
+	src := s.Source()
+	if src == nil {
+		return errors.New("handlers: missing source")
+	}
+
+	if err := src.TakeItem(key); err != nil {
+		return err
+	}
+
+	playingSkip()
+	return s.load(m, src, key)
+}
+
+// Enqueue adds items of the source to play next, before any other item.
+func (s *Session) Enqueue(m *client.MpvIpcClient, entries ...string) error {
+	s.opMtx.Lock()
+	defer s.opMtx.Unlock()
+
+	src := s.Source()
+	if src == nil {
+		return errors.New("handlers: missing source")
+	}
+
+	if err := src.EnqueueItems(entries...); err != nil {
+		return err
+	}
+	if !s.HasNext() {
+		return nil
+	}
+	return s.refreshNext(m, src)
+}
+
+// Queued returns the formatted names of the items enqueued to play next.
+func (s *Session) Queued() ([]string, error) {
+	s.opMtx.Lock()
+	defer s.opMtx.Unlock()
+
+	src := s.Source()
+	if src == nil {
+		return nil, errors.New("handlers: missing source")
+	}
+
+	items, err := src.QueuedItems()
+	if err != nil {
+		return nil, err
+	}
+
+	rv := []string{}
+	for _, item := range items {
+		f, err := src.FormatItem(item)
+		if err != nil {
+			return nil, err
+		}
+		rv = append(rv, f)
+	}
+	return rv, nil
+}
+
+// ClearQueue removes the items enqueued to play next.
+func (s *Session) ClearQueue(m *client.MpvIpcClient) error {
+	s.opMtx.Lock()
+	defer s.opMtx.Unlock()
+
+	src := s.Source()
+	if src == nil {
+		return errors.New("handlers: missing source")
+	}
+
+	if err := src.ClearQueue(); err != nil {
+		return err
+	}
+	if !s.HasNext() {
+		return nil
+	}
+	return s.refreshNext(m, src)
+}
+
+func (s *Session) refreshNext(m *client.MpvIpcClient, src *source.Source) error {
+	next, err := s.lookAhead(src)
+	if err != nil {
+		return err
+	}
+	if err := disp.Set("next", next); err != nil {
+		return err
+	}
+
+	// nothing is playing yet, the item is preloaded after the first one starts
+	if s.getItem().key == "" {
+		return nil
+	}
+	return s.preload(m)
+}
+
+// Preload appends the next item to the mpv playlist, once the current one
+// started playing.
+func (s *Session) Preload(m *client.MpvIpcClient) error {
+	s.opMtx.Lock()
+	defer s.opMtx.Unlock()
+	return s.preload(m)
+}
+
+func (s *Session) load(m *client.MpvIpcClient, src *source.Source, key string) error {
+	total := src.GetItemsCount()
+	idx := total - src.GetCurrentItemsCount()
+
+	// looking ahead picks an item, single entries are loaded only once
+	if s.HasNext() {
+		if _, err := s.lookAhead(src); err != nil {
+			return err
+		}
+	} else {
+		s.mtx.Lock()
+		s.nextKey = ""
+		s.item.next = ""
+		s.mtx.Unlock()
+	}
+
+	file, err := src.GetFile(key)
+	if err != nil {
+		return err
+	}
+
+	start, err := src.GetStartPosition(key)
+	if err != nil {
+		return err
+	}
+
+	current, err := src.FormatItem(key)
+	if err != nil {
+		return err
+	}
+
+	s.mtx.Lock()
+	s.item.key = key
+	s.item.file = file
+	s.item.current = current
+	s.item.sourceName = src.GetBackendName()
+	s.item.idxTotal = total
+	s.item.idxCurrent = idx
+	s.mtx.Unlock()
+
+	if err := m.SetProperty("osd-playing-msg", filepath.ToSlash(current)); err != nil {
+		return err
+	}
+	if err := m.SetProperty("pause", true); err != nil {
+		return err
+	}
+	if err := m.SetProperty("fullscreen", true); err != nil {
+		return err
+	}
+	if err := transformApply(m, s); err != nil {
+		return err
+	}
+
+	if err := speedApply(m, s); err != nil {
+		return err
+	}
+	if err := trackApplyLanguages(m); err != nil {
+		return err
+	}
+
+	// start is a global option, it must be reset for the items that follow
+	if start > 0 || s.startSet {
+		v := "none"
+		if start > 0 {
+			v = strconv.FormatFloat(start, 'f', 3, 64)
+		}
+		if err := m.SetProperty("start", v); err != nil {
+			return err
+		}
+		s.startSet = start > 0
+	}
+
+	s.mtx.Lock()
+	s.waiting = true
+	preloaded := s.preloaded
+	s.preloaded = ""
+	s.mtx.Unlock()
+
+	if preloaded != "" && preloaded == key && playlistNext(m) == file {
+		_, err = m.Command("playlist-next")
+		return err
+	}
+
+	// replacing the file also drops the preloaded one from the playlist
+	_, err = m.Command("loadfile", file)
+	return err
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/rafaelmartins/b8r/internal/mpv/client"
+	"github.com/rafaelmartins/b8r/internal/store"
+	"rafaelmartins.com/p/octokeyz"
+)
+
+var (
+	speedLadder   = []float64{0.25, 0.5, 0.75, 1, 1.25, 1.5, 2, 3, 4}
+	speedHold     = 2.0
+	speedRemember = ""
+
+	kv *store.Store
+)
+
+func init() {
+	actions["speed-up"] = func(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session) error {
+		return speedStep(m, s, 1)
+	}
+	actions["speed-down"] = func(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session) error {
+		return speedStep(m, s, -1)
+	}
+	actions["speed-reset"] = func(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session) error {
+		return speedSet(m, s, 1)
+	}
+
+	holdActions["speed-hold"] = func(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session) *holdCallbacks {
+		prev := 1.0
+		return &holdCallbacks{
+			held: func() error {
+				v, err := m.GetPropertyFloat64("speed")
+				if err != nil {
+					return err
+				}
+				prev = v
+				return m.SetProperty("speed", speedHold)
+			},
+			release: func(held bool) error {
+				if !held {
+					return nil
+				}
+				return m.SetProperty("speed", prev)
+			},
+		}
+	}
+}
+
+func SetStore(s *store.Store) {
+	kv = s
+}
+
+func SetSpeed(ladder []float64, hold float64, remember string) error {
+	if len(ladder) > 0 {
+		for _, v := range ladder {
+			if v <= 0 {
+				return fmt.Errorf("handlers: invalid speed: %g", v)
+			}
+		}
+		speedLadder = slices.Clone(ladder)
+		slices.Sort(speedLadder)
+	}
+
+	if hold < 0 {
+		return fmt.Errorf("handlers: invalid hold speed: %g", hold)
+	}
+	if hold > 0 {
+		speedHold = hold
+	}
+
+	switch remember {
+	case "", "item", "table":
+		speedRemember = remember
+	default:
+		return fmt.Errorf("handlers: invalid speed remember mode: %s", remember)
+	}
+	return nil
+}
+
+func speedKey(s *Session) string {
+	switch speedRemember {
+	case "item":
+		if item := s.getItem(); item.key != "" {
+			return "speed/item/" + item.sourceName + "/" + item.key
+		}
+	case "table":
+		if table := s.Table(); table != "" {
+			return "speed/table/" + table
+		}
+	}
+	return ""
+}
+
+func speedSet(m *client.MpvIpcClient, s *Session, v float64) error {
+	if err := m.SetProperty("speed", v); err != nil {
+		return err
+	}
+
+	key := speedKey(s)
+	if kv == nil || key == "" {
+		return nil
+	}
+	if v == 1 {
+		return kv.Delete(key)
+	}
+	return kv.Set(key, v)
+}
+
+func speedStep(m *client.MpvIpcClient, s *Session, dir int) error {
+	cur, err := m.GetPropertyFloat64("speed")
+	if err != nil {
+		if errors.Is(err, client.ErrMpvPropertyUnavailable) {
+			return nil
+		}
+		return err
+	}
+
+	// the current speed may be out of the ladder, e.g. set by mpv key bindings
+	const eps = 0.001
+	if dir > 0 {
+		for _, v := range speedLadder {
+			if v > cur+eps {
+				return speedSet(m, s, v)
+			}
+		}
+		return nil
+	}
+	for _, v := range slices.Backward(speedLadder) {
+		if v < cur-eps {
+			return speedSet(m, s, v)
+		}
+	}
+	return nil
+}
+
+func speedApply(m *client.MpvIpcClient, s *Session) error {
+	key := speedKey(s)
+	if kv == nil || key == "" {
+		return nil
+	}
+
+	v := 1.0
+	found, err := kv.Get(key, &v)
+	if err != nil {
+		return err
+	}
+
+	// remembering per table keeps the speed of the session for items without
+	// a stored speed, as any speed change is stored immediately.
+	if !found && speedRemember == "table" {
+		return nil
+	}
+	return m.SetProperty("speed", v)
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/rafaelmartins/b8r/internal/mpv/client"
+	"rafaelmartins.com/p/octokeyz"
+)
+
+var (
+	statusMtx      sync.Mutex
+	statusLoopA    = -1.0
+	statusLoopB    = -1.0
+	statusChapter  = -1
+	statusChapters = 0
+	statusSpeed    = 1.0
+)
+
+func init() {
+	actions["ab-loop"] = func(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session) error {
+		_, err := m.Command("ab-loop")
+		return err
+	}
+	actions["ab-loop-a"] = func(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session) error {
+		return setLoopPoint(m, "ab-loop-a")
+	}
+	actions["ab-loop-b"] = func(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session) error {
+		return setLoopPoint(m, "ab-loop-b")
+	}
+	actions["ab-loop-clear"] = func(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session) error {
+		if err := m.SetProperty("ab-loop-a", "no"); err != nil {
+			return err
+		}
+		return m.SetProperty("ab-loop-b", "no")
+	}
+	actions["chapter-next"] = func(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session) error {
+		return addChapter(m, 1)
+	}
+	actions["chapter-prev"] = func(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session) error {
+		return addChapter(m, -1)
+	}
+	actions["frame-step"] = func(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session) error {
+		_, err := m.Command("frame-step")
+		return err
+	}
+	actions["frame-back-step"] = func(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session) error {
+		_, err := m.Command("frame-back-step")
+		return err
+	}
+}
+
+func setLoopPoint(m *client.MpvIpcClient, name string) error {
+	pos, err := m.GetPropertyFloat64("time-pos")
+	if err != nil {
+		if errors.Is(err, client.ErrMpvPropertyUnavailable) {
+			return nil
+		}
+		return err
+	}
+	return m.SetProperty(name, pos)
+}
+
+func addChapter(m *client.MpvIpcClient, v int) error {
+	statusMtx.Lock()
+	chapters := statusChapters
+	statusMtx.Unlock()
+
+	if chapters == 0 {
+		return nil
+	}
+	if _, err := m.Command("add", "chapter", v); err != nil && !errors.Is(err, client.ErrMpvCommand) {
+		return err
+	}
+	return nil
+}
+
+func formatTime(v float64) string {
+	s := int(v)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+func statusLine() string {
+	statusMtx.Lock()
+	defer statusMtx.Unlock()
+
+	rv := []string{}
+	if statusLoopA >= 0 {
+		l := "A-B " + formatTime(statusLoopA) + "-"
+		if statusLoopB >= 0 {
+			l += formatTime(statusLoopB)
+		}
+		rv = append(rv, l)
+	}
+	if statusChapters > 0 {
+		c := "-"
+		if statusChapter >= 0 {
+			c = fmt.Sprint(statusChapter + 1)
+		}
+		rv = append(rv, fmt.Sprintf("Ch %s/%d", c, statusChapters))
+	}
+	if statusSpeed != 1 {
+		rv = append(rv, fmt.Sprintf("%gx", statusSpeed))
+	}
+	return strings.Join(rv, " ")
+}
+
+func statusUpdateDisplay(dev *octokeyz.Device) error {
+	return disp.Set("status", statusLine())
+}
+
+func observeStatus(dev *octokeyz.Device, m *client.MpvIpcClient) error {
+	loopPoint := func(dst *float64) client.PropertyHandler {
+		return func(m *client.MpvIpcClient, property string, value any) error {
+			statusMtx.Lock()
+			*dst = -1
+			if v, ok := value.(float64); ok {
+				*dst = v
+			}
+			statusMtx.Unlock()
+			return statusUpdateDisplay(dev)
+		}
+	}
+
+	if err := m.ObserveProperty("ab-loop-a", loopPoint(&statusLoopA)); err != nil {
+		return err
+	}
+	if err := m.ObserveProperty("ab-loop-b", loopPoint(&statusLoopB)); err != nil {
+		return err
+	}
+
+	// chapter becomes unavailable for files without chapters, and unavailable
+	// properties are not reported. chapters is always available.
+	if err := m.ObserveProperty("chapter", func(m *client.MpvIpcClient, property string, value any) error {
+		if v, ok := value.(float64); ok {
+			statusMtx.Lock()
+			statusChapter = int(v)
+			statusMtx.Unlock()
+		}
+		return statusUpdateDisplay(dev)
+	}); err != nil {
+		return err
+	}
+	if err := m.ObserveProperty("speed", func(m *client.MpvIpcClient, property string, value any) error {
+		if v, ok := value.(float64); ok {
+			statusMtx.Lock()
+			statusSpeed = v
+			statusMtx.Unlock()
+		}
+		return statusUpdateDisplay(dev)
+	}); err != nil {
+		return err
+	}
+	return m.ObserveProperty("chapters", func(m *client.MpvIpcClient, property string, value any) error {
+		if v, ok := value.(float64); ok {
+			statusMtx.Lock()
+			statusChapters = int(v)
+			if statusChapters == 0 {
+				statusChapter = -1
+			}
+			statusMtx.Unlock()
+		}
+		return statusUpdateDisplay(dev)
+	})
+}
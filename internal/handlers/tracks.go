@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rafaelmartins/b8r/internal/mpv/client"
+	"rafaelmartins.com/p/octokeyz"
+)
+
+var (
+	trackAudioLanguage    = ""
+	trackSubtitleLanguage = ""
+)
+
+func init() {
+	actions["audio-next"] = func(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session) error {
+		return trackCycle(dev, m, "aid", "audio")
+	}
+	actions["sub-next"] = func(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session) error {
+		return trackCycle(dev, m, "sid", "sub")
+	}
+	actions["sub-toggle"] = func(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session) error {
+		if err := m.CycleProperty("sub-visibility"); err != nil {
+			return err
+		}
+		visible, err := m.GetPropertyBool("sub-visibility")
+		if err != nil {
+			return err
+		}
+		if !visible {
+			return displayFlash(dev, "S: hidden")
+		}
+		return trackShow(dev, m, "sub")
+	}
+	actions["sub-delay-up"] = func(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session) error {
+		return delayAdd(dev, m, "sub-delay", "Sub delay", 0.1)
+	}
+	actions["sub-delay-down"] = func(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session) error {
+		return delayAdd(dev, m, "sub-delay", "Sub delay", -0.1)
+	}
+	actions["audio-delay-up"] = func(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session) error {
+		return delayAdd(dev, m, "audio-delay", "Audio delay", 0.1)
+	}
+	actions["audio-delay-down"] = func(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session) error {
+		return delayAdd(dev, m, "audio-delay", "Audio delay", -0.1)
+	}
+}
+
+func SetTrackLanguages(audio string, subtitle string) {
+	trackAudioLanguage = audio
+	trackSubtitleLanguage = subtitle
+}
+
+// alang and slang are options, they must be set before loading the file to
+// take effect.
+func trackApplyLanguages(m *client.MpvIpcClient) error {
+	if trackAudioLanguage != "" {
+		if err := m.SetProperty("alang", trackAudioLanguage); err != nil {
+			return err
+		}
+	}
+	if trackSubtitleLanguage != "" {
+		if err := m.SetProperty("slang", trackSubtitleLanguage); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func trackDescription(m *client.MpvIpcClient, typ string) (string, error) {
+	v, err := m.GetProperty("track-list")
+	if err != nil {
+		return "", err
+	}
+	tracks, ok := v.([]any)
+	if !ok {
+		return "", errors.New("handlers: invalid track list")
+	}
+
+	prefix := "A"
+	if typ == "sub" {
+		prefix = "S"
+	}
+
+	total := 0
+	idx := 0
+	desc := ""
+	for _, t := range tracks {
+		track, ok := t.(map[string]any)
+		if !ok || track["type"] != typ {
+			continue
+		}
+		total++
+
+		if selected, _ := track["selected"].(bool); !selected {
+			continue
+		}
+		idx = total
+
+		parts := []string{}
+		for _, k := range []string{"lang", "title"} {
+			if s, ok := track[k].(string); ok && s != "" {
+				parts = append(parts, s)
+			}
+		}
+		desc = strings.Join(parts, " ")
+	}
+
+	if total == 0 {
+		return prefix + ": none", nil
+	}
+	if idx == 0 {
+		return prefix + ": off", nil
+	}
+	if desc == "" {
+		desc = "unknown"
+	}
+	return fmt.Sprintf("%s %d/%d: %s", prefix, idx, total, desc), nil
+}
+
+func trackShow(dev *octokeyz.Device, m *client.MpvIpcClient, typ string) error {
+	desc, err := trackDescription(m, typ)
+	if err != nil {
+		if errors.Is(err, client.ErrMpvPropertyUnavailable) {
+			return nil
+		}
+		return err
+	}
+	return displayFlash(dev, desc)
+}
+
+func trackCycle(dev *octokeyz.Device, m *client.MpvIpcClient, property string, typ string) error {
+	if err := m.CycleProperty(property); err != nil && !errors.Is(err, client.ErrMpvCommand) {
+		return err
+	}
+	return trackShow(dev, m, typ)
+}
+
+func delayAdd(dev *octokeyz.Device, m *client.MpvIpcClient, property string, name string, v float64) error {
+	if err := m.AddProperty(property, v); err != nil {
+		return err
+	}
+	d, err := m.GetPropertyFloat64(property)
+	if err != nil {
+		return err
+	}
+	return displayFlash(dev, fmt.Sprintf("%s: %+.1fs", name, d))
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/rafaelmartins/b8r/internal/mpv/client"
+	"rafaelmartins.com/p/octokeyz"
+)
+
+type transform struct {
+	Zoom   float64 `json:"zoom"`
+	AlignX float64 `json:"align-x"`
+	AlignY float64 `json:"align-y"`
+	Rotate int     `json:"rotate"`
+	Hflip  bool    `json:"hflip,omitempty"`
+	Vflip  bool    `json:"vflip,omitempty"`
+}
+
+func init() {
+	actions["transform-save"] = func(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session) error {
+		return transformSave(dev, m, s)
+	}
+	actions["transform-forget"] = func(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session) error {
+		return transformForget(dev, s)
+	}
+}
+
+// items are identified by their table entry, or by their file for sessions
+// without tables.
+func transformKey(s *Session) string {
+	item := s.getItem()
+	if table := s.Table(); table != "" && item.key != "" {
+		return "transform/table/" + table + "/" + item.key
+	}
+	if item.file != "" {
+		return "transform/file/" + item.file
+	}
+	return ""
+}
+
+func transformGet(m *client.MpvIpcClient) (*transform, error) {
+	rv := &transform{}
+
+	var err error
+	if rv.Zoom, err = m.GetPropertyFloat64("video-zoom"); err != nil {
+		return nil, err
+	}
+	if rv.AlignX, err = m.GetPropertyFloat64("video-align-x"); err != nil {
+		return nil, err
+	}
+	if rv.AlignY, err = m.GetPropertyFloat64("video-align-y"); err != nil {
+		return nil, err
+	}
+	if rv.Rotate, err = m.GetPropertyInt("video-rotate"); err != nil {
+		return nil, err
+	}
+
+	vf, err := m.GetProperty("vf")
+	if err != nil {
+		return nil, err
+	}
+	if filters, ok := vf.([]any); ok {
+		for _, f := range filters {
+			filter, ok := f.(map[string]any)
+			if !ok {
+				continue
+			}
+			if enabled, ok := filter["enabled"].(bool); ok && !enabled {
+				continue
+			}
+			switch filter["name"] {
+			case "hflip":
+				rv.Hflip = true
+			case "vflip":
+				rv.Vflip = true
+			}
+		}
+	}
+	return rv, nil
+}
+
+func transformSet(m *client.MpvIpcClient, t *transform) error {
+	for _, flip := range []struct {
+		name    string
+		enabled bool
+	}{{"hflip", t.Hflip}, {"vflip", t.Vflip}} {
+		if _, err := m.Command("vf", "remove", flip.name); err != nil {
+			return err
+		}
+		if flip.enabled {
+			if _, err := m.Command("vf", "add", flip.name); err != nil {
+				return err
+			}
+		}
+	}
+	if err := m.SetProperty("video-align-x", t.AlignX); err != nil {
+		return err
+	}
+	if err := m.SetProperty("video-align-y", t.AlignY); err != nil {
+		return err
+	}
+	if err := m.SetProperty("video-rotate", t.Rotate); err != nil {
+		return err
+	}
+	return m.SetProperty("video-zoom", t.Zoom)
+}
+
+// transformApply sets the stored transform of the current item, or resets the
+// transform if there's none. stored transforms take precedence over automatic
+// orientation.
+func transformApply(m *client.MpvIpcClient, s *Session) error {
+	t := &transform{}
+	s.setTransformStored(false)
+	if key := transformKey(s); kv != nil && key != "" {
+		found, err := kv.Get(key, t)
+		if err != nil {
+			return err
+		}
+		s.setTransformStored(found)
+	}
+	return transformSet(m, t)
+}
+
+func transformSave(dev *octokeyz.Device, m *client.MpvIpcClient, s *Session) error {
+	key := transformKey(s)
+	if kv == nil || key == "" {
+		return nil
+	}
+
+	t, err := transformGet(m)
+	if err != nil {
+		if errors.Is(err, client.ErrMpvPropertyUnavailable) {
+			return nil
+		}
+		return err
+	}
+	if err := kv.Set(key, t); err != nil {
+		return err
+	}
+	return displayFlash(dev, "Transform saved")
+}
+
+func transformForget(dev *octokeyz.Device, s *Session) error {
+	key := transformKey(s)
+	if kv == nil || key == "" {
+		return nil
+	}
+
+	if err := kv.Delete(key); err != nil {
+		return err
+	}
+	return displayFlash(dev, "Transform forgotten")
+}
@@ -0,0 +1,194 @@
+package hooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rafaelmartins/b8r/internal/utils"
+)
+
+type Event string
+
+const (
+	EventSessionStart   Event = "session-start"
+	EventItemLoaded     Event = "item-loaded"
+	EventItemSkipped    Event = "item-skipped"
+	EventEndFile        Event = "end-file"
+	EventTableExhausted Event = "table-exhausted"
+	EventTableRefilled  Event = "table-refilled"
+	EventQuit           Event = "quit"
+)
+
+var (
+	ErrEventNotSupported = errors.New("hooks: event not supported")
+
+	events = []Event{
+		EventSessionStart,
+		EventItemLoaded,
+		EventItemSkipped,
+		EventEndFile,
+		EventTableExhausted,
+		EventTableRefilled,
+		EventQuit,
+	}
+)
+
+type Payload struct {
+	Event  Event     `json:"event"`
+	Time   time.Time `json:"time"`
+	Source string    `json:"source,omitempty"`
+	Table  string    `json:"table,omitempty"`
+	Entry  string    `json:"entry,omitempty"`
+	File   string    `json:"file,omitempty"`
+	Index  int       `json:"index,omitempty"`
+	Total  int       `json:"total,omitempty"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+type hook struct {
+	event   Event
+	command string
+}
+
+type job struct {
+	hk   *hook
+	data []byte
+}
+
+// Hooks run one at a time, in the order they were fired, by a worker started
+// when there's something to run.
+type Hooks struct {
+	hooks []*hook
+	quit  func(p *Payload)
+
+	mtx     sync.Mutex
+	wg      sync.WaitGroup
+	queue   []*job
+	running bool
+	closed  bool
+}
+
+func New() *Hooks {
+	return &Hooks{}
+}
+
+func (h *Hooks) Add(event string, command string) error {
+	if !slices.Contains(events, Event(event)) {
+		return fmt.Errorf("%w: %s", ErrEventNotSupported, event)
+	}
+	if command == "" {
+		return fmt.Errorf("hooks: %s: command required", event)
+	}
+
+	h.hooks = append(h.hooks, &hook{
+		event:   Event(event),
+		command: command,
+	})
+	return nil
+}
+
+func (h *Hooks) worker() {
+	defer h.wg.Done()
+
+	for {
+		h.mtx.Lock()
+		if len(h.queue) == 0 {
+			h.running = false
+			h.mtx.Unlock()
+			return
+		}
+		j := h.queue[0]
+		h.queue = h.queue[1:]
+		h.mtx.Unlock()
+
+		h.run(j.hk, j.data)
+	}
+}
+
+func (h *Hooks) run(hk *hook, data []byte) {
+	e := utils.Shell(hk.command)
+	e.Stdin = bytes.NewReader(data)
+	e.Stdout = os.Stderr
+	e.Stderr = os.Stderr
+	if err := e.Run(); err != nil {
+		log.Printf("error: hooks: %s: %q: %s", hk.event, hk.command, err)
+	}
+}
+
+// Fire queues the hooks registered for the payload event to run in background,
+// a slow or failing hook must not block playback.
+func (h *Hooks) Fire(p *Payload) {
+	if h == nil || p == nil {
+		return
+	}
+
+	data := []byte(nil)
+	jobs := []*job{}
+	for _, hk := range h.hooks {
+		if hk.event != p.Event {
+			continue
+		}
+
+		if data == nil {
+			pp := *p
+			pp.Time = time.Now()
+
+			var err error
+			data, err = json.Marshal(pp)
+			if err != nil {
+				log.Printf("error: hooks: %s: %s", p.Event, err)
+				return
+			}
+		}
+
+		jobs = append(jobs, &job{
+			hk:   hk,
+			data: data,
+		})
+	}
+	if len(jobs) == 0 {
+		return
+	}
+
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	// mpv may still report events while the session quits, after the quit event
+	if h.closed {
+		return
+	}
+	h.queue = append(h.queue, jobs...)
+	if !h.running {
+		h.running = true
+		h.wg.Add(1)
+		go h.worker()
+	}
+}
+
+// SetQuit sets a function to fill the payload of the quit event, fired when
+// closing, with the source being played by then.
+func (h *Hooks) SetQuit(fn func(p *Payload)) {
+	h.quit = fn
+}
+
+func (h *Hooks) Close() error {
+	p := &Payload{Event: EventQuit}
+	if h.quit != nil {
+		h.quit(p)
+	}
+	h.Fire(p)
+
+	h.mtx.Lock()
+	h.closed = true
+	h.mtx.Unlock()
+
+	h.wg.Wait()
+	return nil
+}
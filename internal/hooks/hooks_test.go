@@ -0,0 +1,83 @@
+//go:build unix
+// +build unix
+
+package hooks
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestHooksOrder(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.jsonl")
+
+	h := New()
+	for _, e := range events {
+		// the slowest hook runs first, it must still be the first to finish
+		cmd := "{ cat; echo; } >> " + out
+		if e == EventItemLoaded {
+			cmd = "sleep 0.2; " + cmd
+		}
+		if err := h.Add(string(e), cmd); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h.SetQuit(func(p *Payload) {
+		p.Source = "local"
+		p.Table = "cats"
+	})
+
+	fired := []Event{EventSessionStart, EventItemLoaded, EventItemSkipped, EventEndFile, EventItemLoaded}
+	for _, e := range fired {
+		h.Fire(&Payload{Event: e, Source: "local"})
+	}
+
+	// the quit hook runs last, and closing waits for every hook
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	h.Fire(&Payload{Event: EventEndFile})
+
+	fp, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fp.Close()
+
+	got := []*Payload{}
+	scanner := bufio.NewScanner(fp)
+	for scanner.Scan() {
+		p := &Payload{}
+		if err := json.Unmarshal(scanner.Bytes(), p); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, p)
+	}
+
+	expected := append(slices.Clone(fired), EventQuit)
+	if len(got) != len(expected) {
+		t.Fatalf("unexpected number of hooks run: %d", len(got))
+	}
+	for i, p := range got {
+		if p.Event != expected[i] {
+			t.Fatalf("unexpected event %d: got %s, want %s", i, p.Event, expected[i])
+		}
+	}
+	if q := got[len(got)-1]; q.Source != "local" || q.Table != "cats" {
+		t.Errorf("unexpected quit payload: %+v", q)
+	}
+}
+
+func TestHooksAddInvalid(t *testing.T) {
+	h := New()
+	if err := h.Add("invalid", "true"); err == nil {
+		t.Error("expected error for unsupported event")
+	}
+	if err := h.Add(string(EventQuit), ""); err == nil {
+		t.Error("expected error for missing command")
+	}
+}
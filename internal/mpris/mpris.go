@@ -0,0 +1,272 @@
+package mpris
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/godbus/dbus/v5/prop"
+)
+
+const (
+	path        = "/org/mpris/MediaPlayer2"
+	rootIface   = "org.mpris.MediaPlayer2"
+	playerIface = "org.mpris.MediaPlayer2.Player"
+	trackPath   = "/io/rgm/b8r/track"
+)
+
+type PlaybackStatus string
+
+const (
+	PlaybackPlaying PlaybackStatus = "Playing"
+	PlaybackPaused  PlaybackStatus = "Paused"
+	PlaybackStopped PlaybackStatus = "Stopped"
+)
+
+func BusName() string {
+	return fmt.Sprintf("%s.b8r.instance%d", rootIface, os.Getpid())
+}
+
+type Player struct {
+	mtx      sync.Mutex
+	conn     *dbus.Conn
+	props    *prop.Properties
+	track    int
+	metadata map[string]dbus.Variant
+
+	NextCallback      func() error
+	PlayCallback      func() error
+	PauseCallback     func() error
+	PlayPauseCallback func() error
+	StopCallback      func() error
+	QuitCallback      func() error
+}
+
+func New() (*Player, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("mpris: %w", err)
+	}
+
+	return &Player{
+		conn:     conn,
+		metadata: map[string]dbus.Variant{},
+	}, nil
+}
+
+func (p *Player) Close() error {
+	p.conn.ReleaseName(BusName())
+	return p.conn.Close()
+}
+
+func call(fn func() error) *dbus.Error {
+	if fn == nil {
+		return nil
+	}
+	if err := fn(); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+type root struct {
+	p *Player
+}
+
+func (r *root) Raise() *dbus.Error {
+	return nil
+}
+
+func (r *root) Quit() *dbus.Error {
+	return call(r.p.QuitCallback)
+}
+
+type player struct {
+	p *Player
+}
+
+func (p *player) Next() *dbus.Error {
+	return call(p.p.NextCallback)
+}
+
+func (p *player) Previous() *dbus.Error {
+	return nil
+}
+
+func (p *player) Pause() *dbus.Error {
+	return call(p.p.PauseCallback)
+}
+
+func (p *player) PlayPause() *dbus.Error {
+	return call(p.p.PlayPauseCallback)
+}
+
+func (p *player) Stop() *dbus.Error {
+	return call(p.p.StopCallback)
+}
+
+func (p *player) Play() *dbus.Error {
+	return call(p.p.PlayCallback)
+}
+
+// exported as Seek, renamed to avoid clashing with io.Seeker
+func (p *player) SeekBy(offset int64) *dbus.Error {
+	return nil
+}
+
+func (p *player) SetPosition(trackID dbus.ObjectPath, position int64) *dbus.Error {
+	return nil
+}
+
+func (p *player) OpenUri(uri string) *dbus.Error {
+	return nil
+}
+
+// properties serves the metadata from the player, as prop stores maps by
+// merging them into the previous value in place, keeping stale keys and racing
+// with the replies being encoded.
+type properties struct {
+	*prop.Properties
+	p *Player
+}
+
+func (p *properties) Get(iface string, property string) (dbus.Variant, *dbus.Error) {
+	if iface == playerIface && property == "Metadata" {
+		return dbus.MakeVariant(p.p.getMetadata()), nil
+	}
+	return p.Properties.Get(iface, property)
+}
+
+func (p *properties) GetAll(iface string) (map[string]dbus.Variant, *dbus.Error) {
+	rv, err := p.Properties.GetAll(iface)
+	if err != nil {
+		return nil, err
+	}
+	if iface == playerIface {
+		rv["Metadata"] = dbus.MakeVariant(p.p.getMetadata())
+	}
+	return rv, nil
+}
+
+// Export publishes the player in the session bus, callbacks must be set before calling it.
+func (p *Player) Export() error {
+	r := &root{p: p}
+	pl := &player{p: p}
+
+	if err := p.conn.Export(r, path, rootIface); err != nil {
+		return fmt.Errorf("mpris: %w", err)
+	}
+	mapping := map[string]string{"SeekBy": "Seek"}
+	if err := p.conn.ExportWithMap(pl, mapping, path, playerIface); err != nil {
+		return fmt.Errorf("mpris: %w", err)
+	}
+
+	methods := introspect.Methods(pl)
+	for i := range methods {
+		if name, found := mapping[methods[i].Name]; found {
+			methods[i].Name = name
+		}
+	}
+
+	props, err := prop.Export(p.conn, path, prop.Map{
+		rootIface: {
+			"CanQuit":             {Value: p.QuitCallback != nil, Emit: prop.EmitTrue},
+			"CanRaise":            {Value: false, Emit: prop.EmitTrue},
+			"HasTrackList":        {Value: false, Emit: prop.EmitTrue},
+			"Identity":            {Value: "b8r", Emit: prop.EmitTrue},
+			"SupportedUriSchemes": {Value: []string{}, Emit: prop.EmitTrue},
+			"SupportedMimeTypes":  {Value: []string{}, Emit: prop.EmitTrue},
+		},
+		playerIface: {
+			"PlaybackStatus": {Value: string(PlaybackStopped), Emit: prop.EmitTrue},
+			"Rate":           {Value: 1.0, Emit: prop.EmitTrue},
+			"Metadata":       {Value: map[string]dbus.Variant{}, Emit: prop.EmitFalse},
+			"Volume":         {Value: 1.0, Emit: prop.EmitTrue},
+			"Position":       {Value: int64(0), Emit: prop.EmitFalse},
+			"MinimumRate":    {Value: 1.0, Emit: prop.EmitTrue},
+			"MaximumRate":    {Value: 1.0, Emit: prop.EmitTrue},
+			"CanGoNext":      {Value: p.NextCallback != nil, Emit: prop.EmitTrue},
+			"CanGoPrevious":  {Value: false, Emit: prop.EmitTrue},
+			"CanPlay":        {Value: p.PlayCallback != nil || p.PlayPauseCallback != nil, Emit: prop.EmitTrue},
+			"CanPause":       {Value: p.PauseCallback != nil || p.PlayPauseCallback != nil, Emit: prop.EmitTrue},
+			"CanSeek":        {Value: false, Emit: prop.EmitTrue},
+			"CanControl":     {Value: true, Emit: prop.EmitConst},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("mpris: %w", err)
+	}
+	p.props = props
+
+	if err := p.conn.Export(&properties{Properties: props, p: p}, path, "org.freedesktop.DBus.Properties"); err != nil {
+		return fmt.Errorf("mpris: %w", err)
+	}
+
+	node := &introspect.Node{
+		Name: path,
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			prop.IntrospectData,
+			{
+				Name:       rootIface,
+				Methods:    introspect.Methods(r),
+				Properties: props.Introspection(rootIface),
+			},
+			{
+				Name:       playerIface,
+				Methods:    methods,
+				Properties: props.Introspection(playerIface),
+			},
+		},
+	}
+	if err := p.conn.Export(introspect.NewIntrospectable(node), path, "org.freedesktop.DBus.Introspectable"); err != nil {
+		return fmt.Errorf("mpris: %w", err)
+	}
+
+	reply, err := p.conn.RequestName(BusName(), dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return fmt.Errorf("mpris: %w", err)
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		return fmt.Errorf("mpris: bus name already taken: %s", BusName())
+	}
+	return nil
+}
+
+func (p *Player) SetPlaybackStatus(status PlaybackStatus) {
+	if p.props == nil {
+		return
+	}
+	p.props.SetMust(playerIface, "PlaybackStatus", string(status))
+}
+
+func (p *Player) getMetadata() map[string]dbus.Variant {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.metadata
+}
+
+func (p *Player) SetMetadata(title string, album string) {
+	if p.props == nil {
+		return
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	// published maps are never modified, replies may still be encoding them
+	md := map[string]dbus.Variant{}
+	if title != "" {
+		p.track++
+		md["mpris:trackid"] = dbus.MakeVariant(dbus.ObjectPath(fmt.Sprintf("%s/%d", trackPath, p.track)))
+		md["xesam:title"] = dbus.MakeVariant(title)
+		if album != "" {
+			md["xesam:album"] = dbus.MakeVariant(album)
+		}
+	}
+	p.metadata = md
+
+	p.conn.Emit(path, "org.freedesktop.DBus.Properties.PropertiesChanged", playerIface, map[string]dbus.Variant{"Metadata": dbus.MakeVariant(md)}, []string{})
+}
@@ -0,0 +1,266 @@
+package mpris
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const busConfig = `<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>session</type>
+  <listen>unix:path=%s</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>
+`
+
+// privateBus starts a dbus-daemon only reachable by the test, and points the
+// session bus to it.
+func privateBus(t *testing.T) *dbus.Conn {
+	t.Helper()
+
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon not found")
+	}
+
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "bus.conf")
+	if err := os.WriteFile(conf, []byte(strings.Replace(busConfig, "%s", filepath.Join(dir, "bus"), 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(daemon, "--config-file="+conf, "--nofork", "--print-address=1")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	addr, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr = strings.TrimSpace(addr)
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", addr)
+
+	conn, err := dbus.Connect(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	return conn
+}
+
+func newTestPlayer(t *testing.T, setup func(p *Player)) *Player {
+	t.Helper()
+
+	p, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if setup != nil {
+		setup(p)
+	}
+	if err := p.Export(); err != nil {
+		p.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		p.Close()
+	})
+	return p
+}
+
+func getProperty(t *testing.T, conn *dbus.Conn, iface string, name string) any {
+	t.Helper()
+
+	v, err := conn.Object(BusName(), path).GetProperty(iface + "." + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v.Value()
+}
+
+func TestPlayerMethods(t *testing.T) {
+	conn := privateBus(t)
+
+	// callbacks run in the goroutines of the dbus connection
+	called := make(chan string, 10)
+	cb := func(name string) func() error {
+		return func() error {
+			called <- name
+			return nil
+		}
+	}
+	newTestPlayer(t, func(p *Player) {
+		p.NextCallback = cb("next")
+		p.PlayCallback = cb("play")
+		p.PauseCallback = cb("pause")
+		p.StopCallback = func() error {
+			return errors.New("stop failed")
+		}
+	})
+
+	obj := conn.Object(BusName(), path)
+
+	tests := []struct {
+		method string
+		called string
+		fail   bool
+	}{
+		{playerIface + ".Next", "next", false},
+		{playerIface + ".Play", "play", false},
+		{playerIface + ".Pause", "pause", false},
+		{playerIface + ".Previous", "", false},
+		{playerIface + ".PlayPause", "", false},
+		{playerIface + ".Stop", "", true},
+		{rootIface + ".Quit", "", false},
+		{rootIface + ".Raise", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			err := obj.Call(tt.method, 0).Err
+			if tt.fail != (err != nil) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			c := ""
+			select {
+			case c = <-called:
+			default:
+			}
+			if c != tt.called {
+				t.Fatalf("unexpected callback: got %q, want %q", c, tt.called)
+			}
+		})
+	}
+
+	if err := obj.Call(playerIface+".Seek", 0, int64(1000)).Err; err != nil {
+		t.Errorf("seek not exported with its mpris name: %s", err)
+	}
+}
+
+func TestPlayerProperties(t *testing.T) {
+	conn := privateBus(t)
+
+	p := newTestPlayer(t, func(p *Player) {
+		p.NextCallback = func() error { return nil }
+		p.PlayPauseCallback = func() error { return nil }
+	})
+
+	tests := []struct {
+		iface    string
+		name     string
+		expected any
+	}{
+		{rootIface, "Identity", "b8r"},
+		{rootIface, "CanQuit", false},
+		{playerIface, "CanGoNext", true},
+		{playerIface, "CanGoPrevious", false},
+		{playerIface, "CanPlay", true},
+		{playerIface, "CanPause", true},
+		{playerIface, "PlaybackStatus", "Stopped"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if v := getProperty(t, conn, tt.iface, tt.name); v != tt.expected {
+				t.Fatalf("unexpected value: got %v, want %v", v, tt.expected)
+			}
+		})
+	}
+
+	p.SetPlaybackStatus(PlaybackPlaying)
+	if v := getProperty(t, conn, playerIface, "PlaybackStatus"); v != "Playing" {
+		t.Errorf("unexpected playback status: %v", v)
+	}
+
+	if err := conn.AddMatchSignal(dbus.WithMatchInterface("org.freedesktop.DBus.Properties"), dbus.WithMatchMember("PropertiesChanged")); err != nil {
+		t.Fatal(err)
+	}
+	signals := make(chan *dbus.Signal, 10)
+	conn.Signal(signals)
+
+	p.SetMetadata("Title", "Album")
+	select {
+	case sig := <-signals:
+		changed := sig.Body[1].(map[string]dbus.Variant)
+		if md, found := changed["Metadata"]; !found || md.Value().(map[string]dbus.Variant)["xesam:title"].Value() != "Title" {
+			t.Errorf("unexpected signal: %v", sig.Body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("metadata change not signaled")
+	}
+
+	md := getProperty(t, conn, playerIface, "Metadata").(map[string]dbus.Variant)
+	if md["xesam:title"].Value() != "Title" || md["xesam:album"].Value() != "Album" {
+		t.Errorf("unexpected metadata: %v", md)
+	}
+	first := md["mpris:trackid"].Value()
+
+	// every item gets its own track id
+	p.SetMetadata("Other", "")
+	md = getProperty(t, conn, playerIface, "Metadata").(map[string]dbus.Variant)
+	if _, found := md["xesam:album"]; found || md["mpris:trackid"].Value() == first {
+		t.Errorf("unexpected metadata: %v", md)
+	}
+
+	p.SetMetadata("", "")
+	if md := getProperty(t, conn, playerIface, "Metadata").(map[string]dbus.Variant); len(md) != 0 {
+		t.Errorf("metadata not cleared: %v", md)
+	}
+}
+
+func TestPlayerIntrospection(t *testing.T) {
+	conn := privateBus(t)
+	newTestPlayer(t, nil)
+
+	var data string
+	if err := conn.Object(BusName(), path).Call("org.freedesktop.DBus.Introspectable.Introspect", 0).Store(&data); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`name="` + playerIface + `"`, `name="Seek"`, `name="PlaybackStatus"`} {
+		if !strings.Contains(data, s) {
+			t.Errorf("introspection data missing %s", s)
+		}
+	}
+	if strings.Contains(data, `name="SeekBy"`) {
+		t.Error("introspection data exposes SeekBy")
+	}
+}
+
+func TestPlayerBusNameTaken(t *testing.T) {
+	privateBus(t)
+	newTestPlayer(t, nil)
+
+	p, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	if err := p.Export(); err == nil {
+		t.Fatal("expected error")
+	}
+}
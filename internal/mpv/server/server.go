@@ -16,7 +16,7 @@ type MpvIpcServer struct {
 	err  error
 }
 
-func New(binary string, id string, idle bool, extraArgs ...string) *MpvIpcServer {
+func New(binary string, socket string, idle bool, extraArgs ...string) *MpvIpcServer {
 	if binary == "" {
 		binary = "mpv"
 	}
@@ -26,8 +26,6 @@ func New(binary string, id string, idle bool, extraArgs ...string) *MpvIpcServer
 		idleV = "yes"
 	}
 
-	socket := getSocket(id)
-
 	return &MpvIpcServer{
 		binary: binary,
 		args: append(
@@ -0,0 +1,90 @@
+package playlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+type Record struct {
+	Time     time.Time `json:"time"`
+	Source   string    `json:"source"`
+	Table    string    `json:"table,omitempty"`
+	Entry    string    `json:"entry"`
+	Watched  float64   `json:"watched"`
+	Duration float64   `json:"duration,omitempty"`
+	Skipped  bool      `json:"skipped"`
+}
+
+type Log struct {
+	mtx sync.Mutex
+	fp  *os.File
+}
+
+func Open(file string) (*Log, error) {
+	fp, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, fmt.Errorf("playlog: %w", err)
+	}
+
+	return &Log{
+		fp: fp,
+	}, nil
+}
+
+func (l *Log) Append(r *Record) error {
+	if l == nil || r == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	// a single write per record, so that concurrent sessions do not interleave lines
+	if _, err := l.fp.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("playlog: %w", err)
+	}
+	return nil
+}
+
+func (l *Log) Close() error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.fp.Close()
+}
+
+func Read(file string) ([]*Record, error) {
+	fp, err := os.Open(file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("playlog: %w", err)
+	}
+	defer fp.Close()
+
+	rv := []*Record{}
+	scanner := bufio.NewScanner(fp)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		// a session killed mid-write leaves a truncated line behind, it is not worth failing for
+		r := &Record{}
+		if err := json.Unmarshal(scanner.Bytes(), r); err != nil {
+			continue
+		}
+		rv = append(rv, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("playlog: %w", err)
+	}
+	return rv, nil
+}
@@ -0,0 +1,59 @@
+package playlog
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAppendRead(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "playlog.jsonl")
+
+	records := []*Record{
+		{Time: time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC), Source: "local", Table: "cats", Entry: "a.mp4", Watched: 100, Duration: 100},
+		{Time: time.Date(2026, 1, 1, 10, 2, 0, 0, time.UTC), Source: "local", Entry: "b.jpg", Watched: 2, Skipped: true},
+	}
+
+	l, err := Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range records {
+		if err := l.Append(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// broken lines, e.g. from a crash while writing, are ignored
+	fp, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fp.WriteString("{\"time\":\n"); err != nil {
+		t.Fatal(err)
+	}
+	fp.Close()
+
+	read, err := Read(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, records) {
+		t.Fatalf("unexpected records: %+v", read)
+	}
+}
+
+func TestReadNotFound(t *testing.T) {
+	records, err := Read(filepath.Join(t.TempDir(), "playlog.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 0 {
+		t.Fatalf("unexpected records: %+v", records)
+	}
+}
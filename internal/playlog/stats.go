@@ -0,0 +1,141 @@
+package playlog
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+const (
+	SkipEarlyRatio    = 0.9
+	SkipEarlyDuration = 5 * time.Second
+)
+
+// Watched returns the seconds watched of an item, and whether it was left
+// early, before most of its duration. items without duration (e.g. images)
+// are measured by the time elapsed since they started.
+func Watched(pos float64, duration float64, elapsed time.Duration) (float64, bool) {
+	if duration > 0 {
+		return pos, pos/duration < SkipEarlyRatio
+	}
+	return elapsed.Seconds(), elapsed < SkipEarlyDuration
+}
+
+type EntryStats struct {
+	Entry   string
+	Plays   int
+	Skips   int
+	Watched float64
+}
+
+type Stats struct {
+	Plays   int
+	Skips   int
+	Watched float64
+	entries map[string]*EntryStats
+}
+
+func (s *Stats) Add(r *Record) {
+	if s.entries == nil {
+		s.entries = map[string]*EntryStats{}
+	}
+
+	e, found := s.entries[r.Entry]
+	if !found {
+		e = &EntryStats{Entry: r.Entry}
+		s.entries[r.Entry] = e
+	}
+
+	s.Plays++
+	e.Plays++
+	if r.Skipped {
+		s.Skips++
+		e.Skips++
+	}
+	s.Watched += r.Watched
+	e.Watched += r.Watched
+}
+
+func (s *Stats) Played(entry string) bool {
+	_, found := s.entries[entry]
+	return found
+}
+
+// NeverPlayed returns the items without records, in the given order.
+func (s *Stats) NeverPlayed(items []string) []string {
+	rv := []string{}
+	for _, item := range items {
+		if !s.Played(item) {
+			rv = append(rv, item)
+		}
+	}
+	return rv
+}
+
+func (s *Stats) top(n int, value func(e *EntryStats) float64) []*EntryStats {
+	rv := []*EntryStats{}
+	for _, e := range s.entries {
+		if value(e) > 0 {
+			rv = append(rv, e)
+		}
+	}
+
+	slices.SortFunc(rv, func(a *EntryStats, b *EntryStats) int {
+		if c := cmp.Compare(value(b), value(a)); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Entry, b.Entry)
+	})
+	if n > 0 && len(rv) > n {
+		rv = rv[:n]
+	}
+	return rv
+}
+
+func (s *Stats) MostWatched(n int) []*EntryStats {
+	return s.top(n, func(e *EntryStats) float64 {
+		return e.Watched
+	})
+}
+
+func (s *Stats) MostSkipped(n int) []*EntryStats {
+	return s.top(n, func(e *EntryStats) float64 {
+		return float64(e.Skips)
+	})
+}
+
+type Summary struct {
+	Tables  map[string]*Stats
+	Sources map[string]*Stats
+	Days    map[string]*Stats
+}
+
+// Summarize groups the records per table, source and day (in loc), only
+// including the records of a table if not empty.
+func Summarize(records []*Record, table string, loc *time.Location) *Summary {
+	rv := &Summary{
+		Tables:  map[string]*Stats{},
+		Sources: map[string]*Stats{},
+		Days:    map[string]*Stats{},
+	}
+
+	for _, r := range records {
+		if table != "" && r.Table != table {
+			continue
+		}
+
+		add := func(m map[string]*Stats, k string) {
+			if k == "" {
+				return
+			}
+			if _, found := m[k]; !found {
+				m[k] = &Stats{}
+			}
+			m[k].Add(r)
+		}
+		add(rv.Days, r.Time.In(loc).Format(time.DateOnly))
+		add(rv.Tables, r.Table)
+		add(rv.Sources, r.Source)
+	}
+	return rv
+}
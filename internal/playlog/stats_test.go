@@ -0,0 +1,167 @@
+package playlog
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWatched(t *testing.T) {
+	tests := []struct {
+		name     string
+		pos      float64
+		duration float64
+		elapsed  time.Duration
+		watched  float64
+		early    bool
+	}{
+		{"start", 0, 100, time.Minute, 0, true},
+		{"before-threshold", 89.9, 100, time.Minute, 89.9, true},
+		{"threshold", 90, 100, time.Minute, 90, false},
+		{"threshold-odd-duration", 27, 30, time.Minute, 27, false},
+		{"after-threshold", 95, 100, time.Minute, 95, false},
+		{"complete", 100, 100, time.Minute, 100, false},
+		{"image-early", 0, 0, 4 * time.Second, 4, true},
+		{"image-threshold", 0, 0, SkipEarlyDuration, 5, false},
+		{"image-late", 0, 0, time.Minute, 60, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			watched, early := Watched(tt.pos, tt.duration, tt.elapsed)
+			if watched != tt.watched || early != tt.early {
+				t.Fatalf("unexpected result: got (%v, %t), want (%v, %t)", watched, early, tt.watched, tt.early)
+			}
+		})
+	}
+}
+
+func entries(l []*EntryStats) []EntryStats {
+	rv := []EntryStats{}
+	for _, e := range l {
+		rv = append(rv, *e)
+	}
+	return rv
+}
+
+func TestSummarize(t *testing.T) {
+	day1 := time.Date(2026, 1, 1, 22, 0, 0, 0, time.UTC)
+	day2 := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+
+	records := []*Record{
+		{Time: day1, Source: "local", Table: "cats", Entry: "a.mp4", Watched: 100, Duration: 100},
+		{Time: day1, Source: "local", Table: "cats", Entry: "b.mp4", Watched: 3, Duration: 100, Skipped: true},
+		{Time: day2, Source: "local", Table: "cats", Entry: "a.mp4", Watched: 50, Duration: 100},
+		{Time: day2, Source: "local", Table: "cats", Entry: "b.mp4", Watched: 1, Duration: 100, Skipped: true},
+		{Time: day2, Source: "fp", Table: "dogs", Entry: "x", Watched: 30, Duration: 30},
+		{Time: day2, Source: "local", Entry: "c.mp4", Watched: 10, Duration: 100, Skipped: true},
+	}
+
+	tests := []struct {
+		name    string
+		table   string
+		loc     *time.Location
+		tables  map[string][2]int
+		sources map[string][2]int
+		days    map[string][2]int
+	}{
+		{
+			"all",
+			"",
+			time.UTC,
+			map[string][2]int{"cats": {4, 2}, "dogs": {1, 0}},
+			map[string][2]int{"local": {5, 3}, "fp": {1, 0}},
+			map[string][2]int{"2026-01-01": {2, 1}, "2026-01-02": {4, 2}},
+		},
+		{
+			"table",
+			"cats",
+			time.UTC,
+			map[string][2]int{"cats": {4, 2}},
+			map[string][2]int{"local": {4, 2}},
+			map[string][2]int{"2026-01-01": {2, 1}, "2026-01-02": {2, 1}},
+		},
+		{
+			"location",
+			"cats",
+			time.FixedZone("UTC+3", 3*60*60),
+			map[string][2]int{"cats": {4, 2}},
+			map[string][2]int{"local": {4, 2}},
+			map[string][2]int{"2026-01-02": {4, 2}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Summarize(records, tt.table, tt.loc)
+
+			for _, g := range []struct {
+				name     string
+				got      map[string]*Stats
+				expected map[string][2]int
+			}{
+				{"tables", s.Tables, tt.tables},
+				{"sources", s.Sources, tt.sources},
+				{"days", s.Days, tt.days},
+			} {
+				got := map[string][2]int{}
+				for k, v := range g.got {
+					got[k] = [2]int{v.Plays, v.Skips}
+				}
+				if !reflect.DeepEqual(got, g.expected) {
+					t.Errorf("unexpected %s: got %v, want %v", g.name, got, g.expected)
+				}
+			}
+		})
+	}
+
+	s := Summarize(records, "", time.UTC)
+	cats := s.Tables["cats"]
+	if cats.Watched != 154 {
+		t.Errorf("unexpected watched: %v", cats.Watched)
+	}
+
+	if l := entries(cats.MostWatched(0)); !reflect.DeepEqual(l, []EntryStats{
+		{Entry: "a.mp4", Plays: 2, Watched: 150},
+		{Entry: "b.mp4", Plays: 2, Skips: 2, Watched: 4},
+	}) {
+		t.Errorf("unexpected most watched: %+v", l)
+	}
+	if l := entries(cats.MostWatched(1)); len(l) != 1 || l[0].Entry != "a.mp4" {
+		t.Errorf("unexpected most watched: %+v", l)
+	}
+
+	// entries never skipped are not listed
+	if l := entries(s.Sources["local"].MostSkipped(0)); !reflect.DeepEqual(l, []EntryStats{
+		{Entry: "b.mp4", Plays: 2, Skips: 2, Watched: 4},
+		{Entry: "c.mp4", Plays: 1, Skips: 1, Watched: 10},
+	}) {
+		t.Errorf("unexpected most skipped: %+v", l)
+	}
+}
+
+func TestNeverPlayed(t *testing.T) {
+	s := &Stats{}
+	for _, e := range []string{"b.mp4", "d.mp4"} {
+		s.Add(&Record{Entry: e})
+	}
+
+	tests := []struct {
+		name     string
+		items    []string
+		expected []string
+	}{
+		{"some", []string{"a.mp4", "b.mp4", "c.mp4", "d.mp4"}, []string{"a.mp4", "c.mp4"}},
+		{"all-played", []string{"b.mp4", "d.mp4"}, []string{}},
+		{"empty", nil, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if never := s.NeverPlayed(tt.items); !reflect.DeepEqual(never, tt.expected) {
+				t.Fatalf("unexpected result: got %v, want %v", never, tt.expected)
+			}
+		})
+	}
+
+	if never := (&Stats{}).NeverPlayed([]string{"a.mp4"}); !reflect.DeepEqual(never, []string{"a.mp4"}) {
+		t.Fatalf("unexpected result for empty stats: %v", never)
+	}
+}
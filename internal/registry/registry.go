@@ -0,0 +1,203 @@
+package registry
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Session struct {
+	ID           string            `json:"id"`
+	PID          int               `json:"pid"`
+	SerialNumber string            `json:"serial-number"`
+	Sockets      map[string]string `json:"sockets"`
+	Started      time.Time         `json:"started"`
+
+	dir   string
+	owned []string
+}
+
+func New(serialNumber string) (*Session, error) {
+	dir, err := getDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	if err := cleanStale(dir); err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	if serialNumber == "" {
+		serialNumber = "UNK"
+	}
+
+	return &Session{
+		ID:           hex.EncodeToString(id),
+		PID:          os.Getpid(),
+		SerialNumber: serialNumber,
+		Sockets:      map[string]string{},
+		Started:      time.Now(),
+		dir:          dir,
+	}, nil
+}
+
+func List() ([]*Session, error) {
+	dir, err := getDir()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "session-*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	rv := []*Session{}
+	for _, f := range files {
+		s, err := open(f)
+		if err != nil {
+			continue
+		}
+		if !processAlive(s.PID) {
+			continue
+		}
+		rv = append(rv, s)
+	}
+
+	slices.SortFunc(rv, func(a *Session, b *Session) int {
+		return a.Started.Compare(b.Started)
+	})
+	return rv, nil
+}
+
+func open(file string) (*Session, error) {
+	fp, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+
+	rv := &Session{}
+	if err := json.NewDecoder(fp).Decode(rv); err != nil {
+		return nil, err
+	}
+	rv.dir = filepath.Dir(file)
+	return rv, nil
+}
+
+func socketPID(name string) (int, bool) {
+	// sockets are named <name>-<serial-number>-<pid>-<id>, and serial numbers may include dashes.
+	parts := strings.Split(strings.TrimSuffix(name, ".socket"), "-")
+	if len(parts) < 4 {
+		return 0, false
+	}
+	pid, err := strconv.Atoi(parts[len(parts)-2])
+	if err != nil {
+		return 0, false
+	}
+	return pid, true
+}
+
+func cleanStale(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "session-*.json"))
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		s, err := open(f)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			// broken session file, most likely from a crash while writing it
+			os.Remove(f)
+			continue
+		}
+		if processAlive(s.PID) {
+			continue
+		}
+		for _, sock := range s.Sockets {
+			if filepath.Dir(sock) == dir {
+				removeSocket(sock)
+			}
+		}
+		os.Remove(f)
+	}
+
+	sockets, err := filepath.Glob(filepath.Join(dir, "*.socket"))
+	if err != nil {
+		return err
+	}
+
+	for _, sock := range sockets {
+		if pid, ok := socketPID(filepath.Base(sock)); ok && !processAlive(pid) {
+			removeSocket(sock)
+		}
+	}
+	return nil
+}
+
+func (s *Session) filename() string {
+	return filepath.Join(s.dir, fmt.Sprintf("session-%d-%s.json", s.PID, s.ID))
+}
+
+func (s *Session) Socket(name string) string {
+	if rv, ok := s.Sockets[name]; ok {
+		return rv
+	}
+
+	rv := getSocket(s.dir, fmt.Sprintf("%s-%s-%d-%s", name, s.SerialNumber, s.PID, s.ID))
+	s.Sockets[name] = rv
+	s.owned = append(s.owned, rv)
+	return rv
+}
+
+func (s *Session) SetSocket(name string, socket string) {
+	s.Sockets[name] = socket
+}
+
+func (s *Session) Publish() error {
+	tmp, err := os.CreateTemp(s.dir, ".session-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	enc := json.NewEncoder(tmp)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(s); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.filename())
+}
+
+func (s *Session) Close() error {
+	for _, sock := range s.owned {
+		removeSocket(sock)
+	}
+	if err := os.Remove(s.filename()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+//go:build unix
+// +build unix
+
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+func getDir() (string, error) {
+	if dir, found := os.LookupEnv("XDG_RUNTIME_DIR"); found && dir != "" {
+		return filepath.Join(dir, "b8r"), nil
+	}
+
+	dir := os.TempDir()
+	if dir == "" {
+		dir = "/tmp"
+	}
+	return filepath.Join(dir, fmt.Sprintf("b8r-%d", os.Getuid())), nil
+}
+
+func getSocket(dir string, name string) string {
+	return filepath.Join(dir, name+".socket")
+}
+
+func removeSocket(socket string) {
+	os.Remove(socket)
+}
+
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,28 @@
+package registry
+
+import (
+	"os"
+	"path/filepath"
+)
+
+func getDir() (string, error) {
+	return filepath.Join(os.TempDir(), "b8r"), nil
+}
+
+func getSocket(dir string, name string) string {
+	return `\\.\pipe\b8r-` + name
+}
+
+func removeSocket(socket string) {}
+
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
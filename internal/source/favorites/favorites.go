@@ -0,0 +1,181 @@
+package favorites
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/danwakefield/fnmatch"
+	"github.com/rafaelmartins/b8r/internal/config"
+	store "github.com/rafaelmartins/b8r/internal/favorites"
+	"github.com/rafaelmartins/b8r/internal/mime"
+)
+
+// favorites are loaded when listing entries or loading a table, instead of reading the store for
+// every item. backends are shared by sources, switching sources loads them again.
+type FavoritesSource struct {
+	mtx  sync.Mutex
+	favs map[int]*store.Favorite
+}
+
+func (f *FavoritesSource) Name() string {
+	return "favorites"
+}
+
+// favorites may point to files from any source, including remote ones.
+func (f *FavoritesSource) Remote() bool {
+	return true
+}
+
+func (f *FavoritesSource) getStore() (*store.Store, error) {
+	conf, err := config.New()
+	if err != nil {
+		return nil, err
+	}
+	return store.New(conf.GetFavoritesFile()), nil
+}
+
+func (f *FavoritesSource) load() ([]*store.Favorite, error) {
+	s, err := f.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	favs, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	m := map[int]*store.Favorite{}
+	for _, fav := range favs {
+		m[fav.ID] = fav
+	}
+
+	f.mtx.Lock()
+	f.favs = m
+	f.mtx.Unlock()
+	return favs, nil
+}
+
+func (f *FavoritesSource) lookup(id int) (*store.Favorite, bool) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	fav, found := f.favs[id]
+	return fav, found
+}
+
+func (f *FavoritesSource) get(key string) (*store.Favorite, error) {
+	id, err := strconv.Atoi(key)
+	if err != nil {
+		return nil, fmt.Errorf("favorites: invalid entry: %s", key)
+	}
+
+	if fav, found := f.lookup(id); found {
+		return fav, nil
+	}
+
+	// added after loading, e.g. enqueued from another session
+	if _, err := f.load(); err != nil {
+		return nil, err
+	}
+	if fav, found := f.lookup(id); found {
+		return fav, nil
+	}
+	return nil, fmt.Errorf("%w: %d", store.ErrNotFound, id)
+}
+
+func (f *FavoritesSource) List(entries []string, recursive bool) ([]string, bool, error) {
+	favs, err := f.load()
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(entries) == 0 {
+		rv := []string{}
+		for _, fav := range favs {
+			rv = append(rv, strconv.Itoa(fav.ID))
+		}
+		return rv, false, nil
+	}
+
+	// entries select favorites by id, title pattern, original source or table
+	rv := []string{}
+	for _, entry := range entries {
+		found := false
+		for _, fav := range favs {
+			id := strconv.Itoa(fav.ID)
+			if entry == id || entry == fav.Source || entry == fav.Table || fnmatch.Match(entry, fav.Title, 0) {
+				found = true
+
+				// a favorite may match several entries
+				if !slices.Contains(rv, id) {
+					rv = append(rv, id)
+				}
+			}
+		}
+		if !found {
+			return nil, false, fmt.Errorf("favorites: invalid entry: %s", entry)
+		}
+	}
+	return rv, len(entries) == 1 && len(rv) == 1 && entries[0] == rv[0], nil
+}
+
+func (f *FavoritesSource) GetFile(key string) (string, error) {
+	fav, err := f.get(key)
+	if err != nil {
+		return "", err
+	}
+	return fav.File, nil
+}
+
+func (f *FavoritesSource) GetMimeType(key string) (string, error) {
+	fav, err := f.get(key)
+	if err != nil {
+		return "", err
+	}
+	return mime.Detect(fav.File)
+}
+
+func (f *FavoritesSource) GetStartPosition(key string) (float64, error) {
+	fav, err := f.get(key)
+	if err != nil {
+		return 0, err
+	}
+	return fav.Position, nil
+}
+
+func (f *FavoritesSource) CompletionHandler(prev string, cur string) []string {
+	s, err := f.getStore()
+	if err != nil {
+		return nil
+	}
+
+	favs, err := s.List()
+	if err != nil {
+		return nil
+	}
+
+	rv := []string{}
+	for _, fav := range favs {
+		if id := strconv.Itoa(fav.ID); strings.HasPrefix(id, cur) {
+			rv = append(rv, id)
+		}
+	}
+	return rv
+}
+
+func (f *FavoritesSource) FormatItem(key string) (string, error) {
+	fav, err := f.get(key)
+	if err != nil {
+		return "", err
+	}
+	return fav.Title, nil
+}
+
+func (f *FavoritesSource) SetItems(items []string) error {
+	_, err := f.load()
+	return err
+}
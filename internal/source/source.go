@@ -2,11 +2,14 @@ package source
 
 import (
 	"errors"
+	"fmt"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 
 	"github.com/rafaelmartins/b8r/internal/dataset"
+	"github.com/rafaelmartins/b8r/internal/source/favorites"
 	"github.com/rafaelmartins/b8r/internal/source/fp"
 	"github.com/rafaelmartins/b8r/internal/source/local"
 )
@@ -22,9 +25,26 @@ type SourceBackend interface {
 	SetItems(items []string) error
 }
 
-var registry = []SourceBackend{
-	&local.LocalSource{},
-	&fp.FpSource{},
+// backends keep the state of the entries listed, every source gets its own
+// instance.
+var backends = []func() SourceBackend{
+	func() SourceBackend { return &local.LocalSource{} },
+	func() SourceBackend { return &fp.FpSource{} },
+	func() SourceBackend { return &favorites.FavoritesSource{} },
+}
+
+// registry is only used for the names and completion of the backends.
+var registry = func() []SourceBackend {
+	rv := []SourceBackend{}
+	for _, b := range backends {
+		rv = append(rv, b())
+	}
+	return rv
+}()
+
+// backends that can start playback of an item from a given position
+type startPositionBackend interface {
+	GetStartPosition(key string) (float64, error)
 }
 
 type Source struct {
@@ -51,9 +71,9 @@ func CompletionHandler(prev string, cur string) []string {
 
 func New(name string) (*Source, error) {
 	var backend SourceBackend
-	for _, r := range registry {
+	for i, r := range registry {
 		if r.Name() == name {
-			backend = r
+			backend = backends[i]()
 			break
 		}
 	}
@@ -156,6 +176,14 @@ func (s *Source) SetEntries(tableDir string, tableName string, tableCreate bool,
 	return single, nil
 }
 
+func (s *Source) SetCallbacks(exhausted func(), refilled func()) error {
+	if s.items == nil {
+		return errors.New("source: items not set")
+	}
+	s.items.SetCallbacks(exhausted, refilled)
+	return nil
+}
+
 func (s *Source) NextItem() (string, error) {
 	if s.items == nil {
 		return "", errors.New("source: items not set")
@@ -163,19 +191,77 @@ func (s *Source) NextItem() (string, error) {
 	return s.items.Next()
 }
 
-func (s *Source) LookAheadItem() (string, bool, error) {
+func (s *Source) LookAheadItem() (string, error) {
 	if s.items == nil {
-		return "", false, errors.New("source: items not set")
+		return "", errors.New("source: items not set")
 	}
+	return s.items.LookAhead()
+}
 
-	rv, err := s.items.LookAhead()
-	if err != nil {
-		if errors.Is(err, dataset.ErrLookAheadNotSupported) {
-			return "", false, nil
+// EnqueueItems adds items to play next, in order. items may be given as keys
+// or as formatted names.
+func (s *Source) EnqueueItems(entries ...string) error {
+	if s.items == nil {
+		return errors.New("source: items not set")
+	}
+
+	items := s.items.GetItems()
+	keys := []string{}
+	for _, e := range entries {
+		key, err := s.resolveItem(items, e)
+		if err != nil {
+			return err
 		}
-		return "", false, err
+		keys = append(keys, key)
 	}
-	return rv, true, nil
+	return s.items.Enqueue(keys...)
+}
+
+func (s *Source) resolveItem(items []string, e string) (string, error) {
+	if slices.Contains(items, e) {
+		return e, nil
+	}
+	for _, item := range items {
+		if f, err := s.backend.FormatItem(item); err == nil && f == e {
+			return item, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", dataset.ErrNotFound, e)
+}
+
+func (s *Source) QueuedItems() ([]string, error) {
+	if s.items == nil {
+		return nil, errors.New("source: items not set")
+	}
+	return s.items.Queued()
+}
+
+func (s *Source) ClearQueue() error {
+	if s.items == nil {
+		return errors.New("source: items not set")
+	}
+	return s.items.ClearQueue()
+}
+
+func (s *Source) RemainingItems() ([]string, error) {
+	if s.items == nil {
+		return nil, errors.New("source: items not set")
+	}
+	return s.items.Remaining()
+}
+
+func (s *Source) TakeItem(key string) error {
+	if s.items == nil {
+		return errors.New("source: items not set")
+	}
+	return s.items.Take(key)
+}
+
+func (s *Source) Close() error {
+	if s.items == nil {
+		return nil
+	}
+	return s.items.Close()
 }
 
 func (s *Source) ForEachItem(f func(e string)) error {
@@ -189,6 +275,13 @@ func (s *Source) GetFile(key string) (string, error) {
 	return s.backend.GetFile(key)
 }
 
+func (s *Source) GetStartPosition(key string) (float64, error) {
+	if b, ok := s.backend.(startPositionBackend); ok {
+		return b.GetStartPosition(key)
+	}
+	return 0, nil
+}
+
 func (s *Source) FormatItem(key string) (string, error) {
 	return s.backend.FormatItem(key)
 }
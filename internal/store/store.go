@@ -0,0 +1,128 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/rafaelmartins/b8r/internal/filelock"
+)
+
+type Store struct {
+	mtx  sync.Mutex
+	file string
+}
+
+func New(file string) *Store {
+	return &Store{
+		file: file,
+	}
+}
+
+// the file is read again for every operation, other sessions may have changed it.
+func (s *Store) read() (map[string]json.RawMessage, error) {
+	data, err := os.ReadFile(s.file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return map[string]json.RawMessage{}, nil
+		}
+		return nil, fmt.Errorf("store: %w", err)
+	}
+
+	rv := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &rv); err != nil {
+		return nil, fmt.Errorf("store: %w", err)
+	}
+	return rv, nil
+}
+
+func (s *Store) write(values map[string]json.RawMessage) error {
+	data, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// readers never see a partially written file
+	tmp, err := os.CreateTemp(filepath.Dir(s.file), ".store-*")
+	if err != nil {
+		return fmt.Errorf("store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.file); err != nil {
+		return fmt.Errorf("store: %w", err)
+	}
+	return nil
+}
+
+// update locks the file for the read-modify-write, as other sessions may be updating it too.
+func (s *Store) update(fn func(values map[string]json.RawMessage) bool) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	l, err := filelock.New(s.file)
+	if err != nil {
+		return fmt.Errorf("store: %w", err)
+	}
+	defer l.Unlock()
+
+	values, err := s.read()
+	if err != nil {
+		return err
+	}
+	if !fn(values) {
+		return nil
+	}
+	return s.write(values)
+}
+
+func (s *Store) Get(key string, v any) (bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	values, err := s.read()
+	if err != nil {
+		return false, err
+	}
+
+	data, found := values[key]
+	if !found {
+		return false, nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("store: %s: %w", key, err)
+	}
+	return true, nil
+}
+
+func (s *Store) Set(key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return s.update(func(values map[string]json.RawMessage) bool {
+		values[key] = data
+		return true
+	})
+}
+
+func (s *Store) Delete(key string) error {
+	return s.update(func(values map[string]json.RawMessage) bool {
+		if _, found := values[key]; !found {
+			return false
+		}
+		delete(values, key)
+		return true
+	})
+}
@@ -0,0 +1,74 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// every store stands for a session, writing to the same file concurrently
+func TestStoreConcurrentSessions(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "store.json")
+
+	wg := sync.WaitGroup{}
+	for i := range 4 {
+		s := New(file)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 25 {
+				if err := s.Set(fmt.Sprintf("key-%d-%d", i, j), j); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	s := New(file)
+	for i := range 4 {
+		for j := range 25 {
+			v := 0
+			found, err := s.Get(fmt.Sprintf("key-%d-%d", i, j), &v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !found || v != j {
+				t.Fatalf("key-%d-%d lost", i, j)
+			}
+		}
+	}
+
+	// temporary files are not left behind
+	entries, err := os.ReadDir(filepath.Dir(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.Name() != "store.json" && e.Name() != "store.json.lock" {
+			t.Errorf("unexpected file: %s", e.Name())
+		}
+	}
+}
+
+func TestStoreDelete(t *testing.T) {
+	s := New(filepath.Join(t.TempDir(), "store.json"))
+
+	if err := s.Delete("missing"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("key", "value"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("key"); err != nil {
+		t.Fatal(err)
+	}
+
+	v := ""
+	if found, err := s.Get("key", &v); err != nil || found {
+		t.Fatalf("key not deleted: %v", err)
+	}
+}
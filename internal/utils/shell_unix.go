@@ -0,0 +1,12 @@
+//go:build unix
+// +build unix
+
+package utils
+
+import (
+	"os/exec"
+)
+
+func Shell(cmd string) *exec.Cmd {
+	return exec.Command("/bin/sh", "-c", cmd)
+}
@@ -0,0 +1,9 @@
+package utils
+
+import (
+	"os/exec"
+)
+
+func Shell(cmd string) *exec.Cmd {
+	return exec.Command("cmd.exe", "/C", cmd)
+}
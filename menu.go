@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/rafaelmartins/b8r/internal/config"
+	"github.com/rafaelmartins/b8r/internal/dataset"
+	"github.com/rafaelmartins/b8r/internal/favorites"
+	"github.com/rafaelmartins/b8r/internal/handlers"
+	"github.com/rafaelmartins/b8r/internal/hooks"
+	"github.com/rafaelmartins/b8r/internal/mpv/client"
+)
+
+func registerMenu(conf *config.Config, m *client.MpvIpcClient, hks *hooks.Hooks, s *handlers.Session) {
+	switchTo := func(name string) func() error {
+		return func() error {
+			opts, err := resolveSource(conf, name, nil)
+			if err != nil {
+				return err
+			}
+			if err := switchSource(conf, m, hks, s, opts); err != nil {
+				return err
+			}
+			if len(opts.ignored) > 0 {
+				return handlers.DisplayFlash(nil, switchMessage(opts))
+			}
+			return nil
+		}
+	}
+
+	handlers.AddMenuSection("Presets", func() ([]*handlers.MenuEntry, error) {
+		rv := []*handlers.MenuEntry{}
+		for _, p := range conf.ListPresets() {
+			rv = append(rv, &handlers.MenuEntry{
+				Label:  p,
+				Select: switchTo(p),
+			})
+		}
+		return rv, nil
+	})
+
+	handlers.AddMenuSection("Tables", func() ([]*handlers.MenuEntry, error) {
+		d, err := conf.GetTablesDirectory()
+		if err != nil {
+			return nil, err
+		}
+
+		tables := dataset.ListTables(d)
+		slices.Sort(tables)
+
+		rv := []*handlers.MenuEntry{}
+		for _, t := range tables {
+			rv = append(rv, &handlers.MenuEntry{
+				Label:  t,
+				Select: switchTo(t),
+			})
+		}
+		return rv, nil
+	})
+
+	handlers.AddMenuSection("Favorites", func() ([]*handlers.MenuEntry, error) {
+		favs, err := favorites.New(conf.GetFavoritesFile()).List()
+		if err != nil {
+			return nil, err
+		}
+
+		rv := []*handlers.MenuEntry{}
+		for _, f := range favs {
+			rv = append(rv, &handlers.MenuEntry{
+				Label: fmt.Sprintf("%d: %s", f.ID, f.Title),
+				Select: func() error {
+					// the name could be shadowed by a table or preset
+					return switchSource(conf, m, hks, s, &sourceOptions{
+						name:    "favorites",
+						source:  "favorites",
+						entries: []string{strconv.Itoa(f.ID)},
+						include: oInclude.Default,
+						exclude: oExclude.Default,
+					})
+				},
+			})
+		}
+		return rv, nil
+	})
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"log"
+
+	"github.com/rafaelmartins/b8r/internal/cleanup"
+	"github.com/rafaelmartins/b8r/internal/handlers"
+	"github.com/rafaelmartins/b8r/internal/mpris"
+	"github.com/rafaelmartins/b8r/internal/mpv/client"
+)
+
+func openMpris(m *client.MpvIpcClient, s *handlers.Session) {
+	mp, err := mpris.New()
+	if err != nil {
+		// no session bus, e.g. running headless
+		log.Printf("warning: %s", err)
+		return
+	}
+	cleanup.Register(mp)
+
+	idle := func() bool {
+		v, err := m.GetPropertyBool("idle-active")
+		return err == nil && v
+	}
+
+	// the source may be switched at runtime, sessions started with a single entry have none
+	withNext := s.HasNext
+
+	mp.NextCallback = func() error {
+		if withNext() {
+			return s.Next(m)
+		}
+		return nil
+	}
+	mp.PlayCallback = func() error {
+		if withNext() && idle() {
+			return s.Next(m)
+		}
+		return handlers.Play(m)
+	}
+	mp.PauseCallback = func() error {
+		return handlers.Pause(m)
+	}
+	mp.PlayPauseCallback = func() error {
+		if withNext() && idle() {
+			return s.Next(m)
+		}
+		return handlers.PlayPause(m)
+	}
+	mp.StopCallback = func() error {
+		return handlers.Stop(m)
+	}
+	mp.QuitCallback = func() error {
+		_, err := m.Command("quit")
+		return err
+	}
+
+	if err := mp.Export(); err != nil {
+		log.Printf("warning: %s", err)
+		return
+	}
+
+	update := func(m *client.MpvIpcClient, property string, value any) error {
+		if idle() {
+			mp.SetPlaybackStatus(mpris.PlaybackStopped)
+			mp.SetMetadata("", "")
+			return nil
+		}
+		if paused, err := m.GetPropertyBool("pause"); err == nil && paused {
+			mp.SetPlaybackStatus(mpris.PlaybackPaused)
+			return nil
+		}
+		mp.SetPlaybackStatus(mpris.PlaybackPlaying)
+		return nil
+	}
+	cleanup.Check(m.ObserveProperty("pause", update))
+	cleanup.Check(m.ObserveProperty("idle-active", update))
+	cleanup.Check(m.ObserveProperty("media-title", func(m *client.MpvIpcClient, property string, value any) error {
+		if title, ok := value.(string); ok && !idle() {
+			mp.SetMetadata(title, s.SourceName())
+		}
+		return nil
+	}))
+}
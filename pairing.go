@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rafaelmartins/b8r/internal/androidtv"
+	"github.com/rafaelmartins/b8r/internal/cli"
+	"github.com/rafaelmartins/b8r/internal/config"
+	"github.com/rafaelmartins/b8r/internal/control"
+	"github.com/rafaelmartins/b8r/internal/handlers"
+	"rafaelmartins.com/p/octokeyz"
+)
+
+var (
+	oPairingCode = &cli.StringOption{
+		Name:    'P',
+		Default: "",
+		Help:    "android-tv pairing code, for non-interactive pairing (also read from $B8R_ATV_PAIRING_CODE)",
+		Metavar: "CODE",
+	}
+
+	pairingMtx    sync.Mutex
+	pairingSubmit func(code string) error
+)
+
+func registerPairingControl(ctl *control.Server) {
+	ctl.AddHandler("pair", func(args []string) (string, error) {
+		if len(args) != 1 {
+			return "", errors.New("usage: pair CODE")
+		}
+
+		pairingMtx.Lock()
+		submit := pairingSubmit
+		pairingMtx.Unlock()
+
+		if submit == nil {
+			return "", errors.New("no android-tv pairing in progress")
+		}
+		return "", submit(args[0])
+	})
+}
+
+// pairAndroidTv gives up after timeout, if not zero.
+func pairAndroidTv(conf *config.Config, d *config.AndroidTv, dev *octokeyz.Device, interactive bool, timeout time.Duration) (err error) {
+	code := oPairingCode.GetValue()
+	if code == "" {
+		code = os.Getenv("B8R_ATV_PAIRING_CODE")
+	}
+	if code != "" {
+		if err := androidtv.ValidatePairingCode(code); err != nil {
+			return err
+		}
+	}
+
+	certFile, exists := conf.GetAndroidTvCertificate(d.Name)
+	if exists {
+		return fmt.Errorf("android-tv certificate already exists, please remove it to pair again")
+	}
+
+	cert, err := androidtv.CreateCertificate(certFile)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// an unpaired certificate is useless, and would prevent pairing again
+		if err != nil {
+			os.Remove(certFile)
+		}
+	}()
+
+	atv, err := androidtv.NewPairing(d.Host, cert, oEvents.GetValue())
+	if err != nil {
+		return err
+	}
+	defer atv.Close()
+
+	// releases the code input when giving up
+	aborted := make(chan struct{})
+	defer close(aborted)
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		expired = t.C
+	}
+
+	atv.SecretCallback = func() (string, error) {
+		if code != "" {
+			return code, nil
+		}
+
+		codes := make(chan string, 1)
+		errCh := make(chan error, 1)
+		done := make(chan struct{})
+		defer close(done)
+
+		submit := func(c string) error {
+			c = strings.TrimSpace(c)
+			if err := androidtv.ValidatePairingCode(c); err != nil {
+				return err
+			}
+			select {
+			case codes <- c:
+				return nil
+			default:
+				return errors.New("android-tv pairing code already submitted")
+			}
+		}
+
+		pairingMtx.Lock()
+		pairingSubmit = submit
+		pairingMtx.Unlock()
+
+		defer func() {
+			pairingMtx.Lock()
+			pairingSubmit = nil
+			pairingMtx.Unlock()
+		}()
+
+		if dev != nil {
+			go func() {
+				c, err := handlers.PairingCodeInput(dev, d.Name, done)
+				if err != nil {
+					select {
+					case <-done:
+					case errCh <- err:
+					}
+					return
+				}
+				submit(c)
+			}()
+		}
+
+		if !interactive {
+			log.Printf("android-tv %s: please enter the code displayed on TV using the macropad or `b8r ctl pair CODE'", d.Name)
+		} else {
+			fmt.Print("Please enter the code displayed on TV: ")
+			go func() {
+				r := bufio.NewReader(os.Stdin)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if err := submit(line); err != nil {
+						if errors.Is(err, androidtv.ErrPairingCodeInvalid) {
+							fmt.Printf("%s, please try again: ", err)
+							continue
+						}
+					}
+					return
+				}
+			}()
+		}
+
+		select {
+		case c := <-codes:
+			return c, nil
+		case err := <-errCh:
+			return "", err
+		case <-aborted:
+			return "", errors.New("android-tv pairing interrupted")
+		}
+	}
+
+	completed := make(chan struct{})
+	atv.CompleteCallback = func() {
+		close(completed)
+	}
+
+	if err := atv.Request(); err != nil {
+		return err
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- atv.Listen()
+	}()
+
+	select {
+	case <-completed:
+		return nil
+	case err := <-errCh:
+		if err == nil {
+			err = errors.New("android-tv pairing interrupted")
+		}
+		return err
+	case <-expired:
+		return fmt.Errorf("android-tv pairing timed out after %s", timeout)
+	}
+}
@@ -5,16 +5,22 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/rafaelmartins/b8r/internal/androidtv"
 	"github.com/rafaelmartins/b8r/internal/cleanup"
 	"github.com/rafaelmartins/b8r/internal/config"
+	"github.com/rafaelmartins/b8r/internal/control"
+	"github.com/rafaelmartins/b8r/internal/favorites"
 	"github.com/rafaelmartins/b8r/internal/handlers"
 	"github.com/rafaelmartins/b8r/internal/mpv/client"
+	"github.com/rafaelmartins/b8r/internal/registry"
+	"github.com/rafaelmartins/b8r/internal/store"
 	"github.com/rafaelmartins/b8r/internal/utils"
 	"rafaelmartins.com/p/octokeyz"
 )
 
+const atvPairingTimeout = 5 * time.Minute
+
 func calledAsPlugin() (bool, uintptr) {
 	if len(os.Args) < 2 {
 		return false, 0
@@ -58,37 +64,113 @@ func pluginInternal(m *client.MpvIpcClient) error {
 	}
 	cleanup.Register(dev)
 
-	if err := utils.IgnoreDisplayMissing(dev.DisplayLine(octokeyz.DisplayLine1, "b8r plugin", octokeyz.DisplayLineAlignCenter)); err != nil {
+	disp, err := openDisplay(conf, dev, "b8r plugin")
+	if err != nil {
+		return err
+	}
+
+	// input handlers must be registered before listening, they are used for pairing
+	if err := handlers.RegisterInputHandlers(dev); err != nil {
+		return err
+	}
+
+	go func() {
+		if err := dev.Listen(nil); err != nil {
+			log.Print(err)
+		}
+	}()
+
+	sess, err := registry.New(dev.SerialNumber())
+	if err != nil {
+		return err
+	}
+	cleanup.Register(sess)
+
+	ctl, err := control.New(sess.Socket("control"))
+	if err != nil {
 		return err
 	}
+	cleanup.Register(ctl)
+	registerPairingControl(ctl)
+
+	go func() {
+		if err := ctl.Listen(); err != nil {
+			log.Print(err)
+		}
+	}()
+
+	if err := sess.Publish(); err != nil {
+		return err
+	}
+
+	atvDevices, err := selectAndroidTvDevices(conf, conf.MpvPlugin.AndroidTv.Devices)
+	cleanup.Check(err)
+
+	type atvPending struct {
+		d       *config.AndroidTv
+		muting  bool
+		pausing bool
+	}
+	pending := []*atvPending{}
 
-	if conf.AndroidTv.Host != "" {
-		atvMuting, err := envConfBool("B8R_MPV_ATV_MUTE", conf.MpvPlugin.AndroidTv.Mute)
+	for _, d := range atvDevices {
+		muting := d.Mute
+		if conf.MpvPlugin.AndroidTv.Mute != nil {
+			muting = *conf.MpvPlugin.AndroidTv.Mute
+		}
+		atvMuting, err := envConfBool("B8R_MPV_ATV_MUTE", muting)
 		cleanup.Check(err)
-		atvPausing, err := envConfBool("B8R_MPV_ATV_PAUSE", conf.MpvPlugin.AndroidTv.Pause)
+
+		pausing := d.Pause
+		if conf.MpvPlugin.AndroidTv.Pause != nil {
+			pausing = *conf.MpvPlugin.AndroidTv.Pause
+		}
+		atvPausing, err := envConfBool("B8R_MPV_ATV_PAUSE", pausing)
 		cleanup.Check(err)
 
-		certFile, exists := conf.GetAndroidTvCertificate()
-		if !exists {
-			cleanup.Check("android-tv certificate not found, please pair by calling this binary with `-p`")
+		// pairing waits for the user, mpv must not wait for it
+		if _, exists := conf.GetAndroidTvCertificate(d.Name); !exists && d.Host != "" {
+			pending = append(pending, &atvPending{
+				d:       d,
+				muting:  atvMuting,
+				pausing: atvPausing,
+			})
+			continue
 		}
 
-		cert, err := androidtv.OpenCertificate(certFile)
+		atv, err := openAndroidTv(conf, d, oEvents.GetValue())
 		cleanup.Check(err)
 
-		atv, err := androidtv.NewRemote(conf.AndroidTv.Host, cert, oEvents.GetValue())
-		cleanup.Check(err)
-		cleanup.Register(atv)
+		handlers.AndroidTvAdd(d.Name, atv, atvMuting, atvPausing)
+	}
 
-		go func() {
-			cleanup.Check(atv.Listen())
-		}()
+	addGuards(conf)
+
+	handlers.SetFavorites(favorites.New(conf.GetFavoritesFile()))
+
+	ssDir, err := conf.GetScreenshotsDirectory()
+	if err != nil {
+		return err
+	}
+	handlers.SetScreenshots(ssDir, conf.Screenshots.Format)
+
+	handlers.SetStore(store.New(conf.GetStoreFile()))
+	if err := handlers.SetSpeed(conf.Speed.Ladder, conf.Speed.Hold, conf.Speed.Remember); err != nil {
+		return err
+	}
 
-		handlers.AndroidTvInit(atv, atvMuting, atvPausing)
+	session, err := handlers.NewSession(nil, nil)
+	if err != nil {
+		return err
+	}
+
+	registerSwitchActions(conf, nil)
+	if err := bindActions(conf); err != nil {
+		return err
 	}
 
 	if err := m.ObserveProperty("filename", func(m *client.MpvIpcClient, property string, value any) error {
-		return utils.IgnoreDisplayMissing(dev.DisplayLine(octokeyz.DisplayLine4, value.(string), octokeyz.DisplayLineAlignLeft))
+		return disp.Set("current", value.(string))
 	}); err != nil {
 		return err
 	}
@@ -97,17 +179,27 @@ func pluginInternal(m *client.MpvIpcClient) error {
 		return err
 	}
 
-	if err := handlers.RegisterOctokeyzHandlers(dev, m, nil, true); err != nil {
-		return err
+	if len(pending) > 0 {
+		// pairings share the code input, one at a time
+		go func() {
+			for _, p := range pending {
+				if err := pairAndroidTv(conf, p.d, dev, false, atvPairingTimeout); err != nil {
+					log.Printf("error: android-tv %s: pairing failed: %s", p.d.Name, err)
+					continue
+				}
+				log.Printf("android-tv %s: paired successfully", p.d.Name)
+
+				atv, err := openAndroidTv(conf, p.d, oEvents.GetValue())
+				if err != nil {
+					log.Printf("error: android-tv %s: %s", p.d.Name, err)
+					continue
+				}
+				handlers.AndroidTvAdd(p.d.Name, atv, p.muting, p.pausing)
+			}
+		}()
 	}
 
-	go func() {
-		if err := dev.Listen(nil); err != nil {
-			log.Print(err)
-		}
-	}()
-
-	return nil
+	return handlers.RegisterOctokeyzHandlers(dev, m, session, true)
 }
 
 func plugin(fd uintptr) {
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/rafaelmartins/b8r/internal/config"
+	"github.com/rafaelmartins/b8r/internal/dataset"
+	"github.com/rafaelmartins/b8r/internal/handlers"
+	"github.com/rafaelmartins/b8r/internal/source"
+)
+
+type sourceOptions struct {
+	name        string
+	source      string
+	table       string
+	tableCreate bool
+	entries     []string
+	mute        bool
+	random      bool
+	recursive   bool
+	start       bool
+	include     string
+	exclude     string
+	androidTv   []string
+	alang       string
+	slang       string
+	autoOrient  bool
+	fit         string
+	fitAlign    string
+
+	// preset settings that only apply when starting a session
+	ignored []string
+}
+
+// checkReservedNames rejects presets named after commands, that couldn't be
+// played from the command line.
+func checkReservedNames(conf *config.Config) error {
+	for _, p := range conf.ListPresets() {
+		if cCli.IsCommand(p) {
+			return fmt.Errorf("preset name is reserved for a command: %s", p)
+		}
+	}
+	return nil
+}
+
+// resolveSource handles a name that may be a table, a preset or a source, in
+// this order. entries are only used by sources.
+func resolveSource(conf *config.Config, name string, entries []string) (*sourceOptions, error) {
+	rv := &sourceOptions{
+		name:      name,
+		mute:      oMute.Default,
+		random:    oRand.Default,
+		recursive: oRecursive.Default,
+		start:     oStart.Default,
+		include:   oInclude.Default,
+		exclude:   oExclude.Default,
+	}
+
+	if d, err := conf.GetTablesDirectory(); err == nil && dataset.TableExists(d, name) {
+		src, err := dataset.TableSource(d, name)
+		if err != nil {
+			return nil, err
+		}
+		rv.source = src
+		rv.table = name
+		return rv, nil
+	}
+
+	p := conf.GetPreset(name)
+	if p == nil {
+		rv.source = name
+		rv.entries = entries
+		return rv, nil
+	}
+
+	rv.source = p.Source
+	rv.entries = p.Entries
+	if p.Mute != nil {
+		rv.mute = *p.Mute
+	}
+	if p.Random != nil {
+		rv.random = *p.Random
+	}
+	if p.Recursive != nil {
+		rv.recursive = *p.Recursive
+	}
+	if p.Start != nil {
+		rv.start = *p.Start
+	}
+	if p.Include != nil {
+		rv.include = *p.Include
+	}
+	if p.Exclude != nil {
+		rv.exclude = *p.Exclude
+	}
+	rv.androidTv = p.AndroidTv
+	if len(p.AndroidTv) > 0 {
+		rv.ignored = append(rv.ignored, "android-tv")
+	}
+	if p.Start != nil && !*p.Start {
+		rv.ignored = append(rv.ignored, "start")
+	}
+	rv.alang = p.Alang
+	rv.slang = p.Slang
+	if p.AutoOrient != nil {
+		rv.autoOrient = *p.AutoOrient
+	}
+	rv.fit = p.Fit
+	rv.fitAlign = p.FitAlign
+	return rv, nil
+}
+
+func (o *sourceOptions) open(conf *config.Config) (*source.Source, bool, error) {
+	src, err := source.New(o.source)
+	if err != nil {
+		return nil, false, err
+	}
+
+	tableDir, err := conf.GetTablesDirectory()
+	if err != nil {
+		return nil, false, err
+	}
+
+	single, err := src.SetEntries(tableDir, o.table, o.tableCreate, o.entries, o.recursive, o.random, o.include, o.exclude)
+	if err != nil {
+		return nil, false, err
+	}
+	return src, single, nil
+}
+
+func (o *sourceOptions) handlers(single bool) *handlers.SourceOptions {
+	return &handlers.SourceOptions{
+		Name:       o.name,
+		Table:      o.table,
+		Single:     single,
+		Mute:       o.mute,
+		Alang:      o.alang,
+		Slang:      o.slang,
+		AutoOrient: o.autoOrient,
+		Fit:        o.fit,
+		FitAlign:   o.fitAlign,
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rafaelmartins/b8r/internal/cleanup"
+	"github.com/rafaelmartins/b8r/internal/cli"
+	"github.com/rafaelmartins/b8r/internal/config"
+	"github.com/rafaelmartins/b8r/internal/control"
+	"github.com/rafaelmartins/b8r/internal/dataset"
+	"github.com/rafaelmartins/b8r/internal/registry"
+	"github.com/rafaelmartins/b8r/internal/source"
+)
+
+var (
+	oQueueClear = &cli.BoolOption{
+		Name:    'c',
+		Default: false,
+		Help:    "clear the queue before adding entries",
+	}
+	aQueueTable = &cli.Argument{
+		Name:     "table",
+		Required: true,
+		Help:     "table to list or add entries to play next",
+		CompletionHandler: func(prev string, cur string) []string {
+			c, err := config.New()
+			if err != nil {
+				return nil
+			}
+
+			d, err := c.GetTablesDirectory()
+			if err != nil {
+				return nil
+			}
+
+			rv := []string{}
+			for _, t := range dataset.ListTables(d) {
+				if strings.HasPrefix(t, cur) {
+					rv = append(rv, t)
+				}
+			}
+			return rv
+		},
+	}
+	aQueueEntries = &cli.Argument{
+		Name:      "entry",
+		Required:  false,
+		Remaining: true,
+		Help:      "one or more entries to play next, as listed by -d",
+	}
+
+	cQueue = &cli.Cli{
+		Name: "queue",
+		Help: "list or add entries to play next from a table",
+		Options: []cli.Option{
+			oQueueClear,
+		},
+		Arguments: []*cli.Argument{
+			aQueueTable,
+			aQueueEntries,
+		},
+	}
+)
+
+func queueCommand() {
+	conf, err := config.New()
+	cleanup.Check(err)
+
+	tableDir, err := conf.GetTablesDirectory()
+	cleanup.Check(err)
+
+	table := aQueueTable.GetValue()
+	if !dataset.TableExists(tableDir, table) {
+		cleanup.Check(fmt.Errorf("table not found: %s", table))
+	}
+
+	name, err := dataset.TableSource(tableDir, table)
+	cleanup.Check(err)
+
+	src, err := source.New(name)
+	cleanup.Check(err)
+
+	// the queue is stored in a file of its own, shared with running sessions
+	_, err = src.SetEntries(tableDir, table, false, nil, false, false, oInclude.Default, oExclude.Default)
+	cleanup.Check(err)
+	cleanup.Register(src)
+
+	// sessions playing the table are asked to change it themselves, to update
+	// the next item they display and preload
+	if sockets := queueSessions(table); len(sockets) > 0 {
+		for _, sock := range sockets {
+			if oQueueClear.GetValue() {
+				_, err := control.Call(sock, "queue-clear")
+				cleanup.Check(err)
+			}
+			if aQueueEntries.IsSet() {
+				_, err := control.Call(sock, append([]string{"enqueue"}, aQueueEntries.GetValues()...)...)
+				cleanup.Check(err)
+			}
+		}
+	} else {
+		if oQueueClear.GetValue() {
+			cleanup.Check(src.ClearQueue())
+		}
+		if aQueueEntries.IsSet() {
+			cleanup.Check(src.EnqueueItems(aQueueEntries.GetValues()...))
+		}
+	}
+
+	queued, err := src.QueuedItems()
+	cleanup.Check(err)
+
+	for _, q := range queued {
+		f, err := src.FormatItem(q)
+		cleanup.Check(err)
+		fmt.Println(f)
+	}
+}
+
+// queueSessions returns the control sockets of the running sessions playing a
+// table. enqueuing is idempotent, so every one of them may get the changes.
+func queueSessions(table string) []string {
+	sessions, err := registry.List()
+	if err != nil {
+		return nil
+	}
+
+	rv := []string{}
+	for _, s := range sessions {
+		sock, found := s.Sockets["control"]
+		if !found {
+			continue
+		}
+
+		status, err := control.Call(sock, "status")
+		if err != nil {
+			continue
+		}
+
+		name, src, t, current := "", "", "", ""
+		if _, err := fmt.Sscanf(status, "name=%q source=%q table=%q current=%q", &name, &src, &t, &current); err != nil {
+			continue
+		}
+		if t == table {
+			rv = append(rv, sock)
+		}
+	}
+	return rv
+}
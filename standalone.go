@@ -1,21 +1,24 @@
 package main
 
 import (
-	"bufio"
 	"fmt"
-	"os"
 	"runtime/debug"
 	"strings"
 
-	"github.com/rafaelmartins/b8r/internal/androidtv"
 	"github.com/rafaelmartins/b8r/internal/cleanup"
 	"github.com/rafaelmartins/b8r/internal/cli"
 	"github.com/rafaelmartins/b8r/internal/config"
+	"github.com/rafaelmartins/b8r/internal/control"
 	"github.com/rafaelmartins/b8r/internal/dataset"
+	"github.com/rafaelmartins/b8r/internal/favorites"
 	"github.com/rafaelmartins/b8r/internal/handlers"
+	"github.com/rafaelmartins/b8r/internal/hooks"
 	"github.com/rafaelmartins/b8r/internal/mpv/client"
 	"github.com/rafaelmartins/b8r/internal/mpv/server"
+	"github.com/rafaelmartins/b8r/internal/playlog"
+	"github.com/rafaelmartins/b8r/internal/registry"
 	"github.com/rafaelmartins/b8r/internal/source"
+	"github.com/rafaelmartins/b8r/internal/store"
 	"github.com/rafaelmartins/b8r/internal/utils"
 	"rafaelmartins.com/p/octokeyz"
 )
@@ -51,20 +54,22 @@ var (
 		Default: false,
 		Help:    "dump mpv/android-tv events (useful for development)",
 	}
-	oPairAndroidTv = &cli.BoolOption{
-		Name:    'p',
-		Default: false,
-		Help:    "pair with android-tv as remote control and exit",
+	oPairAndroidTv = &cli.StringOption{
+		Name:              'p',
+		Default:           "",
+		Help:              "pair with android-tv device NAME as remote control and exit",
+		Metavar:           "NAME",
+		CompletionHandler: completeAndroidTvDevices,
 	}
 	oMuteAndroidTv = &cli.BoolOption{
 		Name:    'u',
 		Default: false,
-		Help:    "mute/unmute android-tv device",
+		Help:    "mute/unmute android-tv devices (overrides device configuration)",
 	}
 	oPauseAndroidTv = &cli.BoolOption{
 		Name:    'a',
 		Default: false,
-		Help:    "pause/unpause android-tv device",
+		Help:    "pause/unpause android-tv devices (overrides device configuration)",
 	}
 	oInclude = &cli.StringOption{
 		Name:    'i',
@@ -97,6 +102,12 @@ var (
 			return rv
 		},
 	}
+	oMpvSocket = &cli.StringOption{
+		Name:    'c',
+		Default: "",
+		Help:    "connect to an already running mpv ipc server instead of spawning one",
+		Metavar: "SOCKET",
+	}
 	oTable = &cli.StringOption{
 		Name:    't',
 		Default: "",
@@ -145,17 +156,28 @@ var (
 			oStart,
 			oEvents,
 			oPairAndroidTv,
+			oPairingCode,
 			oMuteAndroidTv,
 			oPauseAndroidTv,
+			oAndroidTv,
 			oInclude,
 			oExclude,
 			oSerialNumber,
+			oMpvSocket,
 			oTable,
 		},
 		Arguments: []*cli.Argument{
 			aPresetOrSourceOrTable,
 			aEntries,
 		},
+		Commands: []*cli.Cli{
+			cAtv,
+			cCtl,
+			cStats,
+			cFav,
+			cLayout,
+			cQueue,
+		},
 	}
 )
 
@@ -166,47 +188,79 @@ func standalone() {
 	if ok {
 		cCli.Version = bi.Main.Version
 	}
-	cCli.Parse()
+	switch cmd := cCli.Parse(); {
+	case isAtvCommand(cmd):
+		atvCommand(cmd)
+		return
+	case cmd == cCtl:
+		ctlCommand()
+		return
+	case cmd == cStats:
+		statsCommand()
+		return
+	case isFavCommand(cmd):
+		favCommand(cmd)
+		return
+	case cmd == cLayout:
+		layoutCommand()
+		return
+	case cmd == cQueue:
+		queueCommand()
+		return
+	}
 
 	conf, err := config.New()
 	cleanup.Check(err)
+	cleanup.Check(checkReservedNames(conf))
 
-	if oPairAndroidTv.GetValue() {
-		if conf.AndroidTv.Host == "" {
-			cleanup.Check("android-tv host not configured")
+	if oPairAndroidTv.IsSet() {
+		if oPairAndroidTv.GetValue() == "" {
+			cCli.Usage(false, "android-tv device name required")
+			cleanup.Exit(1)
 		}
 
-		certFile, exists := conf.GetAndroidTvCertificate()
-		if exists {
-			cleanup.Check("android-tv certificate already exists, please remove it to pair again")
+		d := conf.GetAndroidTvDevice(oPairAndroidTv.GetValue())
+		if d == nil || d.Host == "" {
+			d, err = pickAndroidTvHost(conf, oPairAndroidTv.GetValue())
+			cleanup.Check(err)
 		}
 
-		cert, err := androidtv.CreateCertificate(certFile)
-		cleanup.Check(err)
+		sn := conf.Standalone.SerialNumber
+		if v := oSerialNumber.GetValue(); v != "" {
+			sn = v
+		}
 
-		atv, err := androidtv.NewPairing(conf.AndroidTv.Host, cert, oEvents.GetValue())
-		cleanup.Check(err)
-		cleanup.Register(atv)
+		// the macropad is optional here, it is only used to input the pairing code
+		var dev *octokeyz.Device
+		if pad, err := octokeyz.GetDevice(sn); err == nil && pad.Open() == nil {
+			dev = pad
+			sn = dev.SerialNumber()
+			cleanup.Register(dev)
+			cleanup.Check(handlers.RegisterInputHandlers(dev))
+			_, err := openDisplay(conf, dev, "b8r")
+			cleanup.Check(err)
 
-		atv.SecretCallback = func() (string, error) {
-			fmt.Print("Please enter the code displayed on TV: ")
-			rv, err := bufio.NewReader(os.Stdin).ReadString('\n')
-			if err != nil {
-				return "", err
-			}
-			if s := strings.TrimSpace(rv); len(s) == 6 {
-				return s, nil
-			}
-			return "", fmt.Errorf("invalid code, must be 6 hexadecimal digits")
+			go func() {
+				cleanup.Check(dev.Listen(nil))
+			}()
 		}
 
-		atv.CompleteCallback = func() {
-			fmt.Println("Paired successfully")
-			cleanup.Exit(0)
-		}
+		sess, err := registry.New(sn)
+		cleanup.Check(err)
+		cleanup.Register(sess)
+
+		ctl, err := control.New(sess.Socket("control"))
+		cleanup.Check(err)
+		cleanup.Register(ctl)
+		registerPairingControl(ctl)
+
+		go func() {
+			cleanup.Check(ctl.Listen())
+		}()
+		cleanup.Check(sess.Publish())
 
-		cleanup.Check(atv.Request())
-		cleanup.Check(atv.Listen())
+		cleanup.Check(pairAndroidTv(conf, d, dev, true, 0))
+		fmt.Println("Paired successfully")
 		return
 	}
 
@@ -216,88 +270,47 @@ func standalone() {
 	}
 
 	entries := []string{}
-	fmute := oMute.Default
-	frand := oRand.Default
-	frecursive := oRecursive.Default
-	fstart := oStart.Default
-	finclude := oInclude.Default
-	fexclude := oExclude.Default
-
-	srcName := ""
-	tableName := ""
-	tableCreate := false
-	if d, err := conf.GetTablesDirectory(); err == nil && dataset.TableExists(d, aPresetOrSourceOrTable.GetValue()) {
-		tableName = aPresetOrSourceOrTable.GetValue()
-		src, err := dataset.TableSource(d, aPresetOrSourceOrTable.GetValue())
-		cleanup.Check(err)
-		srcName = src
-	} else if p := conf.GetPreset(aPresetOrSourceOrTable.GetValue()); p != nil {
-		srcName = p.Source
-		if p.Entries != nil {
-			entries = p.Entries
-		}
-		if p.Mute != nil {
-			fmute = *p.Mute
-		}
-		if p.Random != nil {
-			frand = *p.Random
-		}
-		if p.Recursive != nil {
-			frecursive = *p.Recursive
-		}
-		if p.Start != nil {
-			fstart = *p.Start
-		}
-		if p.Include != nil {
-			finclude = *p.Include
-		}
-		if p.Exclude != nil {
-			fexclude = *p.Exclude
-		}
-	} else {
-		srcName = aPresetOrSourceOrTable.GetValue()
-		if aEntries.IsSet() {
-			entries = aEntries.GetValues()
-		}
+	if aEntries.IsSet() {
+		entries = aEntries.GetValues()
 	}
+	opts, err := resolveSource(conf, aPresetOrSourceOrTable.GetValue(), entries)
+	cleanup.Check(err)
 
-	if tableName == "" && oTable.GetValue() != "" {
-		tableName = oTable.GetValue()
-		tableCreate = true
+	if opts.table == "" && oTable.GetValue() != "" {
+		if cCli.IsCommand(oTable.GetValue()) {
+			cleanup.Check(fmt.Errorf("table name is reserved for a command: %s", oTable.GetValue()))
+		}
+		opts.table = oTable.GetValue()
+		opts.tableCreate = true
 	}
 
 	if oMute.IsSet() {
-		fmute = oMute.GetValue()
+		opts.mute = oMute.GetValue()
 	}
 	if oRand.IsSet() {
-		frand = oRand.GetValue()
+		opts.random = oRand.GetValue()
 	}
 	if oRecursive.IsSet() {
-		frecursive = oRecursive.GetValue()
+		opts.recursive = oRecursive.GetValue()
 	}
 	if oStart.IsSet() {
-		fstart = oStart.GetValue()
+		opts.start = oStart.GetValue()
 	}
 	if oInclude.IsSet() {
-		finclude = oInclude.GetValue()
+		opts.include = oInclude.GetValue()
 	}
 	if oExclude.IsSet() {
-		fexclude = oExclude.GetValue()
+		opts.exclude = oExclude.GetValue()
+	}
+	if oAndroidTv.IsSet() {
+		opts.androidTv = strings.Split(oAndroidTv.GetValue(), ",")
 	}
 
-	src, err := source.New(srcName)
-	cleanup.Check(err)
-
-	tableDir, err := conf.GetTablesDirectory()
-	cleanup.Check(err)
-
-	singleEntry, err := src.SetEntries(tableDir, tableName, tableCreate, entries, frecursive, frand, finclude, fexclude)
+	src, singleEntry, err := opts.open(conf)
 	cleanup.Check(err)
 
-	hsrc := src
 	if singleEntry {
-		hsrc = nil
-		fstart = true
+		opts.start = true
 	}
 
 	if oDump.GetValue() {
@@ -318,59 +331,133 @@ func standalone() {
 
 	cleanup.Check(dev.Open())
 	cleanup.Register(dev)
+	cleanup.Check(handlers.RegisterInputHandlers(dev))
 
-	cleanup.Check(utils.IgnoreDisplayMissing(dev.DisplayLine(octokeyz.DisplayLine1, "b8r", octokeyz.DisplayLineAlignCenter)))
+	_, err = openDisplay(conf, dev, "b8r")
+	cleanup.Check(err)
 	cleanup.Check(utils.LedFlash3Times(dev))
 
-	s := server.New(
-		"mpv",
-		dev.SerialNumber(),
-		true,
-		"--fullscreen",
-		"--image-display-duration=inf",
-		"--loop",
-		"--really-quiet",
-		"--osd-duration=3000",
-	)
-	cleanup.Check(s.Start())
-
-	c, err := client.NewFromSocket(s.GetSocket(), oEvents.GetValue())
+	sess, err := registry.New(dev.SerialNumber())
+	cleanup.Check(err)
+	cleanup.Register(sess)
+
+	ctl, err := control.New(sess.Socket("control"))
 	cleanup.Check(err)
+	cleanup.Register(ctl)
+	registerPairingControl(ctl)
 
+	go func() {
+		cleanup.Check(ctl.Listen())
+	}()
+
+	var s *server.MpvIpcServer
+	socket := conf.Standalone.MpvSocket
+	if v := oMpvSocket.GetValue(); v != "" {
+		socket = v
+	}
+	if socket == "" {
+		s = server.New(
+			"mpv",
+			sess.Socket("mpv"),
+			true,
+			"--fullscreen",
+			"--image-display-duration=inf",
+			"--loop",
+			"--really-quiet",
+			"--osd-duration=3000",
+		)
+		cleanup.Check(s.Start())
+		socket = s.GetSocket()
+	} else {
+		sess.SetSocket("mpv", socket)
+	}
+	cleanup.Check(sess.Publish())
+
+	c, err := client.NewFromSocket(socket, oEvents.GetValue())
+	cleanup.Check(err)
+
+	wait := make(chan bool)
 	go func() {
 		cleanup.Check(c.Listen(nil))
+		close(wait)
 	}()
 
-	if conf.AndroidTv.Host != "" {
-		certFile, exists := conf.GetAndroidTvCertificate()
-		if !exists {
-			cleanup.Check("android-tv certificate not found, please pair by calling this binary with `-p`")
-		}
+	atvDevices, err := selectAndroidTvDevices(conf, opts.androidTv)
+	cleanup.Check(err)
 
-		cert, err := androidtv.OpenCertificate(certFile)
+	for _, d := range atvDevices {
+		atv, err := openAndroidTv(conf, d, oEvents.GetValue())
 		cleanup.Check(err)
 
-		atv, err := androidtv.NewRemote(conf.AndroidTv.Host, cert, oEvents.GetValue())
-		cleanup.Check(err)
-		cleanup.Register(atv)
+		muting := d.Mute
+		if oMuteAndroidTv.IsSet() {
+			muting = oMuteAndroidTv.GetValue()
+		}
+		pausing := d.Pause
+		if oPauseAndroidTv.IsSet() {
+			pausing = oPauseAndroidTv.GetValue()
+		}
 
-		go func() {
-			cleanup.Check(atv.Listen())
-		}()
+		handlers.AndroidTvAdd(d.Name, atv, muting, pausing)
+	}
+
+	addGuards(conf)
+	hks := openHooks(conf, src, opts.table)
+
+	plog, err := playlog.Open(conf.GetPlayLogFile())
+	cleanup.Check(err)
+	cleanup.Register(handlers.SetPlayLog(plog))
+	handlers.SetFavorites(favorites.New(conf.GetFavoritesFile()))
+
+	ssDir, err := conf.GetScreenshotsDirectory()
+	cleanup.Check(err)
+	handlers.SetScreenshots(ssDir, conf.Screenshots.Format)
+
+	handlers.SetStore(store.New(conf.GetStoreFile()))
+	cleanup.Check(handlers.SetSpeed(conf.Speed.Ladder, conf.Speed.Hold, conf.Speed.Remember))
 
-		handlers.AndroidTvInit(atv, oMuteAndroidTv.GetValue(), oPauseAndroidTv.GetValue())
+	session, err := handlers.NewSession(src, opts.handlers(singleEntry))
+	cleanup.Check(err)
+	cleanup.Register(session)
+	if s == nil {
+		cleanup.Register(session.MpvRestorer(c))
 	}
 
-	cleanup.Check(handlers.RegisterMPVHandlers(dev, c, fmute, hsrc != nil))
-	cleanup.Check(handlers.RegisterOctokeyzHandlers(dev, c, hsrc, false))
+	// the session may have been switched to another source by then
+	hks.SetQuit(func(p *hooks.Payload) {
+		p.Source = session.SourceName()
+		p.Table = session.Table()
+	})
+
+	registerSwitchActions(conf, hks)
+	cleanup.Check(bindActions(conf))
+	registerMenu(conf, c, hks, session)
+	registerSwitchControl(ctl, conf, c, hks, session)
+
+	cleanup.Check(handlers.RegisterMPVHandlers(dev, c, session))
+	cleanup.Check(handlers.RegisterOctokeyzHandlers(dev, c, session, false))
+	openMpris(c, session)
 
-	if fstart {
-		cleanup.Check(handlers.LoadNextFile(c, src))
+	hks.Fire(&hooks.Payload{
+		Event:  hooks.EventSessionStart,
+		Source: src.GetBackendName(),
+		Table:  opts.table,
+		Total:  src.GetItemsCount(),
+	})
+
+	if opts.start {
+		cleanup.Check(session.Next(c))
 	}
 
 	go func() {
 		cleanup.Check(dev.Listen(nil))
 	}()
 
-	cleanup.Check(s.Wait())
+	if s != nil {
+		cleanup.Check(s.Wait())
+		return
+	}
+
+	// when attached to an external mpv, the session ends when mpv closes the socket.
+	<-wait
 }
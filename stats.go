@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rafaelmartins/b8r/internal/cleanup"
+	"github.com/rafaelmartins/b8r/internal/cli"
+	"github.com/rafaelmartins/b8r/internal/config"
+	"github.com/rafaelmartins/b8r/internal/dataset"
+	"github.com/rafaelmartins/b8r/internal/playlog"
+)
+
+var (
+	oStatsTop = &cli.StringOption{
+		Name:    'n',
+		Default: "10",
+		Help:    "number of entries to list as most watched/skipped",
+		Metavar: "N",
+	}
+	oStatsTable = &cli.StringOption{
+		Name:    't',
+		Default: "",
+		Help:    "only show statistics for table",
+		Metavar: "TABLE",
+		CompletionHandler: func(cur string) []string {
+			c, err := config.New()
+			if err != nil {
+				return nil
+			}
+
+			d, err := c.GetTablesDirectory()
+			if err != nil {
+				return nil
+			}
+
+			rv := []string{}
+			for _, t := range dataset.ListTables(d) {
+				if strings.HasPrefix(t, cur) {
+					rv = append(rv, t)
+				}
+			}
+			return rv
+		},
+	}
+
+	cStats = &cli.Cli{
+		Name: "stats",
+		Help: "show playback statistics",
+		Options: []cli.Option{
+			oStatsTop,
+			oStatsTable,
+		},
+	}
+)
+
+func formatWatched(v float64) string {
+	return time.Duration(v * float64(time.Second)).Round(time.Second).String()
+}
+
+func printStats(w *tabwriter.Writer, title string, s *playlog.Stats, top int) {
+	fmt.Fprintf(w, "%s\n", title)
+	fmt.Fprintf(w, "  plays: %d, skipped: %d, watched: %s\n", s.Plays, s.Skips, formatWatched(s.Watched))
+
+	if l := s.MostWatched(top); len(l) > 0 {
+		fmt.Fprintln(w, "  most watched:")
+		for _, e := range l {
+			fmt.Fprintf(w, "    %s\t%d plays\t%s\n", formatWatched(e.Watched), e.Plays, e.Entry)
+		}
+	}
+	if l := s.MostSkipped(top); len(l) > 0 {
+		fmt.Fprintln(w, "  most skipped:")
+		for _, e := range l {
+			fmt.Fprintf(w, "    %d skips\t%d plays\t%s\n", e.Skips, e.Plays, e.Entry)
+		}
+	}
+}
+
+func statsCommand() {
+	top, err := strconv.Atoi(oStatsTop.GetValue())
+	if err != nil || top < 0 {
+		cleanup.Check(fmt.Errorf("invalid number of entries: %s", oStatsTop.GetValue()))
+	}
+
+	conf, err := config.New()
+	cleanup.Check(err)
+
+	records, err := playlog.Read(conf.GetPlayLogFile())
+	cleanup.Check(err)
+
+	table := oStatsTable.GetValue()
+	summary := playlog.Summarize(records, table, time.Local)
+	days := summary.Days
+	tables := summary.Tables
+	sources := summary.Sources
+
+	tableDir, err := conf.GetTablesDirectory()
+	cleanup.Check(err)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	names := dataset.ListTables(tableDir)
+	if table != "" {
+		names = []string{table}
+	}
+	slices.Sort(names)
+	for _, t := range names {
+		s, found := tables[t]
+		if !found {
+			s = &playlog.Stats{}
+		}
+
+		src, _ := dataset.TableSource(tableDir, t)
+		printStats(w, fmt.Sprintf("table %s (%s):", t, src), s, top)
+
+		// only tables know all of their entries, sources depend on the entries of each session
+		items, err := dataset.TableItems(tableDir, t)
+		cleanup.Check(err)
+
+		if never := s.NeverPlayed(items); len(never) > 0 {
+			fmt.Fprintf(w, "  never played: %d\n", len(never))
+			for _, item := range never {
+				fmt.Fprintf(w, "    %s\n", item)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+
+	if table == "" {
+		keys := []string{}
+		for k := range sources {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			printStats(w, fmt.Sprintf("source %s:", k), sources[k], top)
+			fmt.Fprintln(w)
+		}
+	}
+
+	keys := []string{}
+	for k := range days {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	if len(keys) > 0 {
+		fmt.Fprintln(w, "DAY\tPLAYS\tSKIPPED\tWATCHED")
+		for _, k := range keys {
+			fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", k, days[k].Plays, days[k].Skips, formatWatched(days[k].Watched))
+		}
+	}
+	cleanup.Check(w.Flush())
+}
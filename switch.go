@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rafaelmartins/b8r/internal/config"
+	"github.com/rafaelmartins/b8r/internal/control"
+	"github.com/rafaelmartins/b8r/internal/handlers"
+	"github.com/rafaelmartins/b8r/internal/hooks"
+	"github.com/rafaelmartins/b8r/internal/mpv/client"
+	"rafaelmartins.com/p/octokeyz"
+)
+
+func switchSource(conf *config.Config, m *client.MpvIpcClient, hks *hooks.Hooks, s *handlers.Session, opts *sourceOptions) error {
+	src, single, err := opts.open(conf)
+	if err != nil {
+		return err
+	}
+	// a single item would be picked again and again, refilling the source
+	if src.GetItemsCount() == 1 {
+		single = true
+	}
+
+	// the session keeps the source once switched, even if loading its first item fails
+	err = s.Switch(m, src, opts.handlers(single))
+	if s.Source() != src {
+		if err := src.Close(); err != nil {
+			log.Printf("error: %s", err)
+		}
+		return err
+	}
+	hooksWatchSource(hks, src, opts.table)
+	return err
+}
+
+// switchMessage is flashed on the display after switching to a preset.
+func switchMessage(opts *sourceOptions) string {
+	rv := "Preset: " + opts.name
+	if len(opts.ignored) > 0 {
+		rv += " (" + strings.Join(opts.ignored, ", ") + " ignored)"
+	}
+	return rv
+}
+
+func registerSwitchActions(conf *config.Config, hks *hooks.Hooks) {
+	cycle := func(step int) handlers.Action {
+		return func(dev *octokeyz.Device, m *client.MpvIpcClient, s *handlers.Session) error {
+			// mpv plugin sessions play whatever mpv loads
+			if s.Source() == nil {
+				return errors.New("presets can't be switched without source")
+			}
+
+			presets := conf.ListPresets()
+			if len(presets) == 0 {
+				return errors.New("no presets defined")
+			}
+
+			// sessions not started from a preset move to the first or last one
+			i := slices.Index(presets, s.Name())
+			if i < 0 && step < 0 {
+				i = 0
+			}
+			name := presets[(i+step+len(presets))%len(presets)]
+
+			opts, err := resolveSource(conf, name, nil)
+			if err != nil {
+				return err
+			}
+			if err := switchSource(conf, m, hks, s, opts); err != nil {
+				return err
+			}
+			return handlers.DisplayFlash(dev, switchMessage(opts))
+		}
+	}
+
+	handlers.AddAction("preset-next", cycle(1))
+	handlers.AddAction("preset-prev", cycle(-1))
+}
+
+func registerSwitchControl(ctl *control.Server, conf *config.Config, m *client.MpvIpcClient, hks *hooks.Hooks, s *handlers.Session) {
+	ctl.AddHandler("switch", func(args []string) (string, error) {
+		if len(args) == 0 {
+			return "", errors.New("usage: switch PRESET_OR_SOURCE_OR_TABLE [ENTRY...]")
+		}
+
+		opts, err := resolveSource(conf, args[0], args[1:])
+		if err != nil {
+			return "", err
+		}
+		if err := switchSource(conf, m, hks, s, opts); err != nil {
+			return "", err
+		}
+		if len(opts.ignored) > 0 {
+			return "ignored: " + strings.Join(opts.ignored, ", "), nil
+		}
+		return "", nil
+	})
+
+	ctl.AddHandler("enqueue", func(args []string) (string, error) {
+		if len(args) == 0 {
+			return "", errors.New("usage: enqueue ENTRY...")
+		}
+		return "", s.Enqueue(m, args...)
+	})
+
+	ctl.AddHandler("queue", func(args []string) (string, error) {
+		if len(args) != 0 {
+			return "", errors.New("usage: queue")
+		}
+
+		queued, err := s.Queued()
+		if err != nil {
+			return "", err
+		}
+
+		rv := []string{}
+		for _, q := range queued {
+			rv = append(rv, strconv.Quote(q))
+		}
+		return strings.Join(rv, " "), nil
+	})
+
+	ctl.AddHandler("queue-clear", func(args []string) (string, error) {
+		if len(args) != 0 {
+			return "", errors.New("usage: queue-clear")
+		}
+		return "", s.ClearQueue(m)
+	})
+
+	ctl.AddHandler("status", func(args []string) (string, error) {
+		if len(args) != 0 {
+			return "", errors.New("usage: status")
+		}
+		return fmt.Sprintf("name=%q source=%q table=%q current=%q", s.Name(), s.SourceName(), s.Table(), s.Current()), nil
+	})
+}